@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+	"github.com/brutella/hap/service"
+)
+
+var alarmSensorKinds = []string{"LeakSensor", "SmokeSensor", "CarbonMonoxideSensor"}
+
+// AlarmSensor is a Grenton digital input connected to leak, smoke or CO detector.
+// Unlike motion it is latched: detected state is kept until the input is cleared.
+type AlarmSensor struct {
+	SensorObject
+
+	// Invert is a config option, reverses input state (for normally closed detector outputs)
+	Invert bool `json:",omitempty"`
+
+	State bool
+
+	sensorKind string
+
+	hkAccessory *accessory.A
+	hkService   *service.S
+	hkDetected  *characteristic.Int
+}
+
+func (as *AlarmSensor) Init(clu *Clu, sensorKind string) *accessory.A {
+	as.clu = clu
+	as.sensorKind = sensorKind
+
+	as.Req = ReqObject{
+		Kind: sensorKind,
+		Clu:  as.clu.Id,
+		Id:   as.GetMixedId(),
+	}
+	return as.appendHk()
+}
+
+func (as *AlarmSensor) GetA() *accessory.A {
+	return as.hkAccessory
+}
+
+func (as *AlarmSensor) appendHk() *accessory.A {
+	info := accessory.Info{
+		Name:         as.Name,
+		SerialNumber: fmt.Sprintf("%d", as.Id),
+		Manufacturer: "Grenton",
+		Model:        as.Kind,
+	}
+
+	as.hkAccessory = accessory.New(info, accessory.TypeSensor)
+	as.hkAccessory.Id = as.GetLongId()
+
+	switch as.sensorKind {
+	case "LeakSensor":
+		svc := service.NewLeakSensor()
+		as.hkService, as.hkDetected = svc.S, svc.LeakDetected.Int
+	case "SmokeSensor":
+		svc := service.NewSmokeSensor()
+		as.hkService, as.hkDetected = svc.S, svc.SmokeDetected.Int
+	default:
+		svc := service.NewCarbonMonoxideSensor()
+		as.hkService, as.hkDetected = svc.S, svc.CarbonMonoxideDetected.Int
+	}
+
+	as.addFault(as.hkService)
+	as.hkAccessory.AddS(as.hkService)
+	as.hkDetected.SetValue(0)
+
+	as.clu.set.Logf("HK %s added (id: %x)", as.sensorKind, as.hkAccessory.Id)
+
+	return as.hkAccessory
+}
+
+// Set updates input state, it is also called by InputServer
+func (as *AlarmSensor) Set(state bool) {
+	as.State = state
+
+	// detected value is 1 for every sensor kind (leak, smoke and abnormal CO levels)
+	if state != as.Invert {
+		as.hkDetected.SetValue(1)
+	} else {
+		as.hkDetected.SetValue(0)
+	}
+}
+
+// LoadReqObject checks object received from http request end reads it into AlarmSensor
+func (as *AlarmSensor) LoadReqObject(obj ReqObject) error {
+	var loaded *AlarmSensor
+	switch obj.Kind {
+	case "LeakSensor":
+		loaded = obj.LeakSensor
+	case "SmokeSensor":
+		loaded = obj.SmokeSensor
+	case "CarbonMonoxideSensor":
+		loaded = obj.CarbonMonoxideSensor
+	}
+
+	if err := as.checkLoaded(obj, as.sensorKind, loaded == nil); err != nil {
+		return err
+	}
+
+	as.Set(loaded.State)
+
+	return nil
+}
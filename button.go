@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+	"github.com/brutella/hap/service"
+)
+
+// Button is a Grenton push button presented as HomeKit stateless programmable switch,
+// events are pushed by CLU to InputServer /event endpoint
+type Button struct {
+	CluObject
+
+	hkAccessory *accessory.A
+	hkService   *service.StatelessProgrammableSwitch
+}
+
+func (bt *Button) Init(clu *Clu) *accessory.A {
+	bt.clu = clu
+	return bt.appendHk()
+}
+
+func (bt *Button) GetA() *accessory.A {
+	return bt.hkAccessory
+}
+
+func (bt *Button) appendHk() *accessory.A {
+	info := accessory.Info{
+		Name:         bt.Name,
+		SerialNumber: fmt.Sprintf("%d", bt.Id),
+		Manufacturer: "Grenton",
+		Model:        bt.Kind,
+	}
+
+	bt.hkAccessory = accessory.New(info, accessory.TypeProgrammableSwitch)
+	bt.hkAccessory.Id = bt.GetLongId()
+
+	bt.hkService = service.NewStatelessProgrammableSwitch()
+	bt.hkAccessory.AddS(bt.hkService.S)
+
+	bt.clu.set.Logf("HK StatelessProgrammableSwitch added (id: %x)", bt.hkAccessory.Id)
+
+	return bt.hkAccessory
+}
+
+// Trigger sends button event (single, double or long) to HomeKit
+func (bt *Button) Trigger(event string) error {
+	var hkEvent int
+
+	switch strings.ToLower(event) {
+	case "single", "click":
+		hkEvent = characteristic.ProgrammableSwitchEventSinglePress
+	case "double", "doubleclick":
+		hkEvent = characteristic.ProgrammableSwitchEventDoublePress
+	case "long", "hold":
+		hkEvent = characteristic.ProgrammableSwitchEventLongPress
+	default:
+		return fmt.Errorf("Button Trigger: unknown event (%s)", event)
+	}
+
+	bt.clu.set.Debugf("Button %s triggered event: %s\n", bt.GetMixedId(), event)
+	bt.hkService.ProgrammableSwitchEvent.SetValue(hkEvent)
+
+	return nil
+}
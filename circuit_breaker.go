@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var errGateUnavailable = errors.New("GATE unavailable, circuit breaker is open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (bs breakerState) String() string {
+	switch bs {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker stops requests to unreachable GATE after Threshold consecutive failures,
+// after ProbePeriod single probe request is let through, success closes the breaker
+type circuitBreaker struct {
+	Threshold   int
+	ProbePeriod time.Duration
+
+	// changed is called on every state change
+	changed func(from, to breakerState)
+	// now returns current time, time.Now is used when not set
+	now func() time.Time
+
+	state    breakerState
+	failures int
+	openedAt time.Time
+	block    sync.Mutex
+}
+
+// Allow returns true when request can be sent, when breaker is open only one probe request is allowed
+func (cb *circuitBreaker) Allow() bool {
+	cb.block.Lock()
+	defer cb.block.Unlock()
+
+	switch cb.state {
+	case breakerOpen:
+		if cb.clock().Sub(cb.openedAt) < cb.ProbePeriod {
+			return false
+		}
+		cb.setState(breakerHalfOpen)
+		return true
+	case breakerHalfOpen:
+		// probe is already in progress
+		return false
+	default:
+		return true
+	}
+}
+
+// State returns current breaker state
+func (cb *circuitBreaker) State() breakerState {
+	cb.block.Lock()
+	defer cb.block.Unlock()
+
+	return cb.state
+}
+
+// Success closes the breaker
+func (cb *circuitBreaker) Success() {
+	cb.block.Lock()
+	defer cb.block.Unlock()
+
+	cb.failures = 0
+	cb.setState(breakerClosed)
+}
+
+// Failure counts failed request, breaker opens after Threshold failures or when probe failed
+func (cb *circuitBreaker) Failure() {
+	cb.block.Lock()
+	defer cb.block.Unlock()
+
+	cb.failures++
+	if cb.state == breakerHalfOpen || cb.failures >= cb.Threshold {
+		cb.openedAt = cb.clock()
+		cb.setState(breakerOpen)
+	}
+}
+
+func (cb *circuitBreaker) clock() time.Time {
+	if cb.now != nil {
+		return cb.now()
+	}
+	return time.Now()
+}
+
+func (cb *circuitBreaker) setState(state breakerState) {
+	if cb.state == state {
+		return
+	}
+
+	from := cb.state
+	cb.state = state
+	if cb.changed != nil {
+		cb.changed(from, state)
+	}
+}
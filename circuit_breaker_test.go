@@ -0,0 +1,174 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type breakerChange struct {
+	from, to breakerState
+}
+
+func newTestBreaker(threshold int, probe time.Duration) (*circuitBreaker, *fakeClock, *[]breakerChange) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	changes := &[]breakerChange{}
+
+	cb := &circuitBreaker{
+		Threshold:   threshold,
+		ProbePeriod: probe,
+		now:         clock.Now,
+		changed: func(from, to breakerState) {
+			*changes = append(*changes, breakerChange{from, to})
+		},
+	}
+	return cb, clock, changes
+}
+
+func checkBreaker(t *testing.T, cb *circuitBreaker, state breakerState, allow bool) {
+	t.Helper()
+	if got := cb.State(); got != state {
+		t.Fatalf("state = %s, want %s", got, state)
+	}
+	if got := cb.Allow(); got != allow {
+		t.Fatalf("Allow() = %v in state %s, want %v", got, state, allow)
+	}
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	cb, clock, changes := newTestBreaker(3, 30*time.Second)
+
+	cb.Failure()
+	cb.Failure()
+	checkBreaker(t, cb, breakerClosed, true)
+
+	cb.Failure()
+	checkBreaker(t, cb, breakerOpen, false)
+
+	clock.Advance(29 * time.Second)
+	checkBreaker(t, cb, breakerOpen, false)
+
+	want := []breakerChange{{breakerClosed, breakerOpen}}
+	if !reflect.DeepEqual(*changes, want) {
+		t.Fatalf("changes = %v, want %v", *changes, want)
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	cb, clock, changes := newTestBreaker(1, 30*time.Second)
+
+	cb.Failure()
+	clock.Advance(30 * time.Second)
+
+	// first request after probe period is the probe, others are rejected until it is finished
+	checkBreaker(t, cb, breakerOpen, true)
+	checkBreaker(t, cb, breakerHalfOpen, false)
+	checkBreaker(t, cb, breakerHalfOpen, false)
+
+	// failed probe opens the breaker for another probe period
+	cb.Failure()
+	clock.Advance(29 * time.Second)
+	checkBreaker(t, cb, breakerOpen, false)
+	clock.Advance(time.Second)
+	checkBreaker(t, cb, breakerOpen, true)
+
+	cb.Success()
+	checkBreaker(t, cb, breakerClosed, true)
+	checkBreaker(t, cb, breakerClosed, true)
+
+	want := []breakerChange{
+		{breakerClosed, breakerOpen},
+		{breakerOpen, breakerHalfOpen},
+		{breakerHalfOpen, breakerOpen},
+		{breakerOpen, breakerHalfOpen},
+		{breakerHalfOpen, breakerClosed},
+	}
+	if !reflect.DeepEqual(*changes, want) {
+		t.Fatalf("changes = %v, want %v", *changes, want)
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	cb, _, changes := newTestBreaker(3, 30*time.Second)
+
+	cb.Failure()
+	cb.Failure()
+	cb.Success()
+	cb.Failure()
+	cb.Failure()
+	checkBreaker(t, cb, breakerClosed, true)
+
+	cb.Failure()
+	checkBreaker(t, cb, breakerOpen, false)
+
+	if len(*changes) != 1 {
+		t.Fatalf("changes = %v, want only closed to open", *changes)
+	}
+}
+
+func TestGateBrokerBackoffBounds(t *testing.T) {
+	gb := &GateBroker{RetryDelay: 500 * time.Millisecond}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		full := gb.RetryDelay << attempt
+		for i := 0; i < 100; i++ {
+			delay := gb.backoff(attempt)
+			if delay < full/2 || delay > full {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", attempt, delay, full/2, full)
+			}
+		}
+	}
+}
+
+func TestGateBrokerWithoutRetries(t *testing.T) {
+	gate, posted := newTestGate(t, http.StatusInternalServerError)
+	gb := newTestSetter(&testUpdater{}, gate.URL)
+	gb.Retries = 0
+	gb.RetryDelay = time.Millisecond
+
+	_, err := gb.send(t.Context(), []ReqObject{{Clu: "CLU_test", Id: "DOU0001", Kind: "Light"}})
+	if err == nil {
+		t.Fatal("send error = nil, want GATE error")
+	}
+	if got := len(posted()); got != 1 {
+		t.Fatalf("posted %d requests, want 1 (no retries)", got)
+	}
+}
+
+// newTestConfig returns GrentonSet loaded from config with given options, state files are kept in test directory
+func newTestConfig(t *testing.T, options string) *GrentonSet {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	config := `{` + options + `, "HkPath": "` + filepath.Join(dir, "hk") + `"}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	gs := &GrentonSet{}
+	if err := gs.Config(path); err != nil {
+		t.Fatalf("Config: %v", err)
+	}
+	return gs
+}
+
+func TestConfigReadRetriesDisabled(t *testing.T) {
+	gs := newTestConfig(t, `"ReadRetries": -1`)
+	if gs.broker.Retries != 0 {
+		t.Fatalf("broker Retries = %d, want 0 for ReadRetries -1", gs.broker.Retries)
+	}
+}
+
+func TestConfigSeparateBreakers(t *testing.T) {
+	gs := newTestConfig(t, `"BreakerThreshold": 2`)
+
+	for i := 0; i < gs.BreakerThreshold; i++ {
+		gs.setter.breaker.Failure()
+	}
+	checkBreaker(t, gs.writeBreaker, breakerOpen, false)
+
+	// failing writes do not make GATE unavailable for reads
+	checkBreaker(t, gs.broker.breaker, breakerClosed, true)
+}
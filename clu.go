@@ -14,9 +14,36 @@ type Clu struct {
 	Name string
 
 	Lights        []*Light
+	Dimmers       []*Dimmer
+	Leds          []*Led
 	Therms        []*Thermo
 	Shutters      []*Shutter
 	MotionSensors []*MotionSensor
+	Switches      []*Switch
+	GarageDoors   []*GarageDoor
+	DoorLocks     []*DoorLock
+	Fans          []*Fan
+	Valves        []*Valve
+
+	SecuritySystems []*SecuritySystem
+	Scenes          []*Scene
+
+	// OccupancySensors aggregate MotionSensors of a room
+	OccupancySensors []*OccupancySensor
+
+	// Irrigation is optional, when set all Valves are grouped in one irrigation system accessory
+	Irrigation *IrrigationSystem
+
+	ContactSensors     []*ContactSensor
+	TemperatureSensors []*TemperatureSensor
+	HumiditySensors    []*HumiditySensor
+	LightSensors       []*LightSensor
+
+	LeakSensors  []*AlarmSensor
+	SmokeSensors []*AlarmSensor
+	CoSensors    []*AlarmSensor
+
+	Buttons []*Button
 
 	set   *GrentonSet
 	block sync.Mutex
@@ -46,6 +73,14 @@ func (gc *Clu) InitAll() {
 		light.clu = gc
 		light.InitAll()
 	}
+	for _, dimmer := range gc.Dimmers {
+		dimmer.clu = gc
+		dimmer.InitAll()
+	}
+	for _, led := range gc.Leds {
+		led.clu = gc
+		led.InitAll()
+	}
 
 	for _, thermo := range gc.Therms {
 		thermo.clu = gc
@@ -54,10 +89,80 @@ func (gc *Clu) InitAll() {
 	for _, mos := range gc.MotionSensors {
 		mos.Init(gc)
 	}
+	for _, ocs := range gc.OccupancySensors {
+		ocs.Init(gc)
+	}
+	for _, cos := range gc.ContactSensors {
+		cos.Init(gc)
+	}
+	for _, tes := range gc.TemperatureSensors {
+		tes.Init(gc)
+	}
+	for _, hus := range gc.HumiditySensors {
+		hus.Init(gc)
+	}
+	for _, lis := range gc.LightSensors {
+		lis.Init(gc)
+	}
+	for _, kind := range alarmSensorKinds {
+		for _, als := range gc.AlarmSensors(kind) {
+			als.Init(gc, kind)
+		}
+	}
+	for _, btn := range gc.Buttons {
+		btn.Init(gc)
+	}
 	for _, sht := range gc.Shutters {
 		sht.clu = gc
 		sht.InitAll()
 	}
+	for _, sw := range gc.Switches {
+		sw.clu = gc
+		sw.InitAll()
+	}
+	for _, gdo := range gc.GarageDoors {
+		gdo.clu = gc
+		gdo.InitAll()
+	}
+	for _, dlo := range gc.DoorLocks {
+		dlo.clu = gc
+		dlo.InitAll()
+	}
+	for _, fan := range gc.Fans {
+		fan.clu = gc
+		fan.InitAll()
+	}
+	for _, sec := range gc.SecuritySystems {
+		sec.clu = gc
+		sec.InitAll()
+	}
+	for _, scn := range gc.Scenes {
+		scn.clu = gc
+		scn.InitAll()
+	}
+	for _, vlv := range gc.Valves {
+		vlv.Init(gc)
+	}
+	if gc.Irrigation != nil {
+		gc.Irrigation.Init(gc, gc.Valves)
+	} else {
+		for _, vlv := range gc.Valves {
+			vlv.appendHk()
+		}
+	}
+}
+
+// AlarmSensors returns list of alarm sensors of selected kind (LeakSensor, SmokeSensor or CarbonMonoxideSensor)
+func (gc *Clu) AlarmSensors(kind string) []*AlarmSensor {
+	switch kind {
+	case "LeakSensor":
+		return gc.LeakSensors
+	case "SmokeSensor":
+		return gc.SmokeSensors
+	case "CarbonMonoxideSensor":
+		return gc.CoSensors
+	}
+	return nil
 }
 
 func (gc *Clu) GetAllHkAcc() (slc []*accessory.A) {
@@ -66,16 +171,70 @@ func (gc *Clu) GetAllHkAcc() (slc []*accessory.A) {
 	for _, light := range gc.Lights {
 		slc = append(slc, light.hk.A)
 	}
+	for _, dimmer := range gc.Dimmers {
+		slc = append(slc, dimmer.hk.A)
+	}
+	for _, led := range gc.Leds {
+		slc = append(slc, led.hk.A)
+	}
 
 	for _, thermo := range gc.Therms {
-		slc = append(slc, thermo.hk.A)
+		slc = append(slc, thermo.GetA())
 	}
 	for _, mos := range gc.MotionSensors {
 		slc = append(slc, mos.GetA())
 	}
+	for _, ocs := range gc.OccupancySensors {
+		slc = append(slc, ocs.GetA())
+	}
+	for _, cos := range gc.ContactSensors {
+		slc = append(slc, cos.GetA())
+	}
+	for _, tes := range gc.TemperatureSensors {
+		slc = append(slc, tes.GetA())
+	}
+	for _, hus := range gc.HumiditySensors {
+		slc = append(slc, hus.GetA())
+	}
+	for _, lis := range gc.LightSensors {
+		slc = append(slc, lis.GetA())
+	}
+	for _, kind := range alarmSensorKinds {
+		for _, als := range gc.AlarmSensors(kind) {
+			slc = append(slc, als.GetA())
+		}
+	}
+	for _, btn := range gc.Buttons {
+		slc = append(slc, btn.GetA())
+	}
 	for _, sht := range gc.Shutters {
 		slc = append(slc, sht.hk.A)
 	}
+	for _, sw := range gc.Switches {
+		slc = append(slc, sw.GetA())
+	}
+	for _, gdo := range gc.GarageDoors {
+		slc = append(slc, gdo.hk.A)
+	}
+	for _, dlo := range gc.DoorLocks {
+		slc = append(slc, dlo.GetA())
+	}
+	for _, fan := range gc.Fans {
+		slc = append(slc, fan.GetA())
+	}
+	for _, sec := range gc.SecuritySystems {
+		slc = append(slc, sec.hk.A)
+	}
+	for _, scn := range gc.Scenes {
+		slc = append(slc, scn.hk.A)
+	}
+	if gc.Irrigation != nil {
+		slc = append(slc, gc.Irrigation.GetA())
+	} else {
+		for _, vlv := range gc.Valves {
+			slc = append(slc, vlv.GetA())
+		}
+	}
 
 	return
 }
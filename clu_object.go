@@ -2,13 +2,22 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/brutella/hap"
+	"github.com/brutella/hap/characteristic"
 )
 
+// writeDebounce is a time for collecting HomeKit writes of one change sent as separate characteristics
+// (ex. on and brightness of a slider move) into one GATE request
+const writeDebounce = 100 * time.Millisecond
+
 type ReqObject struct {
 	Clu    string
 	Id     string
@@ -18,14 +27,38 @@ type ReqObject struct {
 
 	Thermo       *Thermo       `json:",omitempty"`
 	Light        *Light        `json:",omitempty"`
+	Dimmer       *Dimmer       `json:",omitempty"`
+	Led          *Led          `json:",omitempty"`
 	Shutter      *Shutter      `json:",omitempty"`
 	MotionSensor *MotionSensor `json:",omitempty"`
+	Switch       *Switch       `json:",omitempty"`
+	GarageDoor   *GarageDoor   `json:",omitempty"`
+	DoorLock     *DoorLock     `json:",omitempty"`
+	Fan          *Fan          `json:",omitempty"`
+	Valve        *Valve        `json:",omitempty"`
+
+	SecuritySystem *SecuritySystem `json:",omitempty"`
+	Scene          *Scene          `json:",omitempty"`
+
+	ContactSensor     *ContactSensor     `json:",omitempty"`
+	TemperatureSensor *TemperatureSensor `json:",omitempty"`
+	HumiditySensor    *HumiditySensor    `json:",omitempty"`
+	LightSensor       *LightSensor       `json:",omitempty"`
+
+	LeakSensor           *AlarmSensor `json:",omitempty"`
+	SmokeSensor          *AlarmSensor `json:",omitempty"`
+	CarbonMonoxideSensor *AlarmSensor `json:",omitempty"`
 }
 
 func (ro ReqObject) Equal(to ReqObject) bool {
 	return strings.EqualFold(ro.Clu, to.Clu) && strings.EqualFold(ro.Id, to.Id) && strings.EqualFold(ro.Kind, to.Kind) && strings.EqualFold(ro.Cmd, to.Cmd)
 }
 
+// SameObject checks if both requests refer to the same Grenton object, command is not compared (GATE responses have none)
+func (ro ReqObject) SameObject(to ReqObject) bool {
+	return strings.EqualFold(ro.Clu, to.Clu) && strings.EqualFold(ro.Id, to.Id) && strings.EqualFold(ro.Kind, to.Kind)
+}
+
 type CluObject struct {
 	Id   uint32
 	Name string
@@ -34,6 +67,8 @@ type CluObject struct {
 	Req ReqObject `json:"-"`
 
 	clu *Clu `json:"-"`
+	// unavailable is set when GATE request for the object failed, cleared by next successful read
+	unavailable atomic.Bool
 }
 
 func (co *CluObject) GetLongId() uint64 {
@@ -51,6 +86,25 @@ func (co *CluObject) GetMixedId() string {
 	return fmt.Sprintf("%s%04d", co.Kind, co.Id)
 }
 
+// SetFault marks object unavailable after failed GATE request, or available again
+func (co *CluObject) SetFault(fault bool) {
+	co.unavailable.Store(fault)
+}
+
+// guardWrites makes HomeKit writes of characteristics fail while object is unavailable,
+// so value is not set optimistically when GATE does not respond (for services without StatusFault)
+func (co *CluObject) guardWrites(cs ...*characteristic.C) {
+	for _, c := range cs {
+		c.SetValueRequestFunc = func(interface{}, *http.Request) (interface{}, int) {
+			if co.unavailable.Load() {
+				co.clu.set.Logf("CluObject %s | %s: HomeKit write rejected, object unavailable", co.Name, co.GetMixedId())
+				return nil, hap.JsonStatusServiceCommunicationFailure
+			}
+			return nil, 0
+		}
+	}
+}
+
 func (co *CluObject) TestGrentonGate(ro ReqObject) bool {
 	co.clu.block.Lock()
 	defer co.clu.block.Unlock()
@@ -96,17 +150,26 @@ func (co *CluObject) Update() error {
 
 }
 
+// SendReq sends request to GATE and returns object state received in response,
+// it gives up after gateRequestTimeout, request queued before shutdown is still sent
 func (gl *CluObject) SendReq(input ReqObject) (result ReqObject, err error) {
+	ctx, cancel := gl.clu.set.requestContext(gateRequestTimeout)
+	defer cancel()
+
+	return gl.SendReqContext(ctx, input)
+}
+
+// SendReqContext sends request to GATE and returns object state received in response
+func (gl *CluObject) SendReqContext(ctx context.Context, input ReqObject) (result ReqObject, err error) {
 
 	if input.Cmd == "" {
 		input.Cmd = "SET"
 	}
 
-	errors := make(chan error)
-
-	gl.clu.set.setter.Queue(errors, input)
-
-	err = <-errors
+	data, err := gl.clu.set.setter.Do(ctx, input)
+	if err == nil && len(data) > 0 {
+		result = data[0]
+	}
 
 	return
 
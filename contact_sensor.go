@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+	"github.com/brutella/hap/service"
+)
+
+type ContactSensor struct {
+	SensorObject
+
+	// Invert is a config option, reverses input state (open input means closed contact)
+	Invert bool `json:",omitempty"`
+
+	State bool
+
+	hkAccessory *accessory.A
+	hkService   *service.ContactSensor
+}
+
+func (cs *ContactSensor) Init(clu *Clu) *accessory.A {
+	cs.clu = clu
+
+	cs.Req = ReqObject{
+		Kind: "ContactSensor",
+		Clu:  cs.clu.Id,
+		Id:   cs.GetMixedId(),
+	}
+	return cs.appendHk()
+}
+
+func (cs *ContactSensor) GetA() *accessory.A {
+	return cs.hkAccessory
+}
+
+func (cs *ContactSensor) appendHk() *accessory.A {
+	info := accessory.Info{
+		Name:         cs.Name,
+		SerialNumber: fmt.Sprintf("%d", cs.Id),
+		Manufacturer: "Grenton",
+		Model:        cs.Kind,
+	}
+
+	cs.hkAccessory = accessory.New(info, accessory.TypeSensor)
+	cs.hkAccessory.Id = cs.GetLongId()
+
+	cs.hkService = service.NewContactSensor()
+	cs.addFault(cs.hkService.S)
+	cs.hkAccessory.AddS(cs.hkService.S)
+	cs.hkService.ContactSensorState.SetValue(cs.getHkState())
+
+	cs.clu.set.Logf("HK ContactSensor added (id: %x)", cs.hkAccessory.Id)
+
+	return cs.hkAccessory
+}
+
+// getHkState returns contact state in HomeKit characteristic format, taking Invert option into account
+func (cs *ContactSensor) getHkState() int {
+	if cs.State != cs.Invert {
+		return characteristic.ContactSensorStateContactDetected
+	}
+	return characteristic.ContactSensorStateContactNotDetected
+}
+
+// Set updates input state, it is also called by InputServer
+func (cs *ContactSensor) Set(state bool) {
+	cs.State = state
+	cs.hkService.ContactSensorState.SetValue(cs.getHkState())
+}
+
+// LoadReqObject checks object received from http request end reads it into ContactSensor
+func (cs *ContactSensor) LoadReqObject(obj ReqObject) error {
+	if err := cs.checkLoaded(obj, "ContactSensor", obj.ContactSensor == nil); err != nil {
+		return err
+	}
+
+	cs.Set(obj.ContactSensor.State)
+
+	return nil
+}
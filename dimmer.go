@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+)
+
+type Dimmer struct {
+	CluObject
+
+	State      bool
+	Brightness int
+
+	lastBrightness int
+
+	hk           *accessory.Lightbulb
+	hkBrightness *characteristic.Brightness
+
+	sendTimer *time.Timer
+	pending   bool
+	block     sync.Mutex
+}
+
+func (gd *Dimmer) LoadReqObject(obj ReqObject) error {
+	if obj.Kind != "Dimmer" {
+		return fmt.Errorf("Dimmer LoadReqObject: wrong object kind (%s)", obj.Kind)
+	}
+
+	if obj.Dimmer == nil {
+		return fmt.Errorf("Dimmer LoadReqObject: missing Dimmer object")
+	}
+
+	gd.SetFault(false)
+
+	gd.block.Lock()
+	defer gd.block.Unlock()
+
+	// values set in HomeKit and not sent yet are newer
+	if gd.pending {
+		return nil
+	}
+
+	gd.Brightness = clampPercent(obj.Dimmer.Brightness)
+	gd.State = gd.Brightness > 0
+	if gd.State {
+		gd.lastBrightness = gd.Brightness
+	}
+	gd.Sync()
+
+	return nil
+}
+
+func (gd *Dimmer) InitAll() {
+	gd.Req = ReqObject{
+		Kind: "Dimmer",
+		Clu:  gd.clu.Id,
+		Id:   gd.GetMixedId(),
+	}
+	gd.lastBrightness = 100
+	gd.AppendHk()
+}
+
+func (gd *Dimmer) AppendHk() *accessory.Lightbulb {
+	info := accessory.Info{
+		Name:         gd.Name,
+		SerialNumber: fmt.Sprintf("%d", gd.Id),
+		Manufacturer: "Grenton",
+		Model:        gd.Kind,
+	}
+
+	gd.hk = accessory.NewLightbulb(info)
+	gd.hk.Id = gd.GetLongId()
+
+	gd.hkBrightness = characteristic.NewBrightness()
+	gd.hk.Lightbulb.AddC(gd.hkBrightness.C)
+
+	gd.hk.Lightbulb.On.OnValueRemoteUpdate(gd.SetOn)
+	gd.hkBrightness.OnValueRemoteUpdate(gd.SetBrightness)
+	gd.guardWrites(gd.hk.Lightbulb.On.C, gd.hkBrightness.C)
+
+	gd.clu.set.Logf("HK Lightbulb (dimmer) added (id: %x, type: %d", gd.hk.A.Id, gd.hk.A.Type)
+	return gd.hk
+}
+
+func (gd *Dimmer) Sync() {
+	gd.hk.Lightbulb.On.SetValue(gd.State)
+	if gd.State {
+		gd.hkBrightness.SetValue(gd.Brightness)
+	}
+}
+
+// SetOn switches dimmer on (restoring last known brightness) or off
+func (gd *Dimmer) SetOn(state bool) {
+	gd.block.Lock()
+	defer gd.block.Unlock()
+
+	if state == gd.State {
+		return
+	}
+
+	if state {
+		gd.Brightness = gd.lastBrightness
+	} else {
+		gd.Brightness = 0
+	}
+	gd.State = state
+
+	gd.send()
+}
+
+// SetBrightness sets dimmer level in percents, 0 turns dimmer off
+func (gd *Dimmer) SetBrightness(brightness int) {
+	gd.block.Lock()
+	defer gd.block.Unlock()
+
+	gd.Brightness = clampPercent(brightness)
+	gd.State = gd.Brightness > 0
+	if gd.State {
+		gd.lastBrightness = gd.Brightness
+	}
+
+	gd.send()
+}
+
+// send schedules request to GATE, HomeKit sends on and brightness of one change separately,
+// so request is delayed by writeDebounce and covers both (must be called with block locked)
+func (gd *Dimmer) send() {
+	gd.pending = true
+	if gd.sendTimer == nil {
+		gd.sendTimer = time.AfterFunc(writeDebounce, gd.flush)
+		return
+	}
+	gd.sendTimer.Reset(writeDebounce)
+}
+
+// flush sends current level to GATE
+func (gd *Dimmer) flush() {
+	gd.block.Lock()
+	gd.pending = false
+
+	req := gd.Req
+	// request is marshalled by GateBroker goroutine, so it gets a copy of values
+	req.Dimmer = &Dimmer{
+		CluObject:  CluObject{Id: gd.Id, Name: gd.Name, Kind: gd.Kind},
+		State:      gd.State,
+		Brightness: gd.Brightness,
+	}
+	gd.block.Unlock()
+
+	_, err := gd.SendReq(req)
+
+	if err != nil {
+		gd.clu.set.Error(fmt.Errorf("Dimmer send: %w", err))
+	}
+}
+
+func clampPercent(value int) int {
+	if value < 0 {
+		return 0
+	}
+	if value > 100 {
+		return 100
+	}
+	return value
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+	"github.com/brutella/hap/service"
+)
+
+const defaultDoorLockUnlockTime = 5
+
+// DoorLock is an electric strike driven by Grenton output (Id) with optional door contact input
+type DoorLock struct {
+	CluObject
+
+	// Input is a config option, mixed id (ex. DIN0012) of door contact active when door is closed
+	Input string `json:",omitempty"`
+	// UnlockTime is a config option, time in seconds the output is on after unlocking
+	UnlockTime int `json:",omitempty"`
+
+	State  bool
+	Closed bool
+
+	unlocked    bool
+	relockTimer *time.Timer
+	block       sync.Mutex
+
+	hkAccessory *accessory.A
+	hkService   *service.LockMechanism
+}
+
+func (dl *DoorLock) InitAll() {
+	dl.Req = ReqObject{
+		Kind:   "DoorLock",
+		Clu:    dl.clu.Id,
+		Id:     dl.GetMixedId(),
+		Source: dl.Input,
+	}
+
+	if dl.UnlockTime <= 0 {
+		dl.UnlockTime = defaultDoorLockUnlockTime
+	}
+	dl.Closed = true
+
+	dl.AppendHk()
+}
+
+func (dl *DoorLock) GetA() *accessory.A {
+	return dl.hkAccessory
+}
+
+func (dl *DoorLock) AppendHk() *accessory.A {
+	info := accessory.Info{
+		Name:         dl.Name,
+		SerialNumber: fmt.Sprintf("%d", dl.Id),
+		Manufacturer: "Grenton",
+		Model:        dl.Kind,
+	}
+
+	dl.hkAccessory = accessory.New(info, accessory.TypeDoorLock)
+	dl.hkAccessory.Id = dl.GetLongId()
+
+	dl.hkService = service.NewLockMechanism()
+	dl.hkAccessory.AddS(dl.hkService.S)
+
+	dl.hkService.LockTargetState.OnValueRemoteUpdate(dl.SetTarget)
+	dl.guardWrites(dl.hkService.LockTargetState.C)
+	dl.Sync()
+
+	dl.clu.set.Logf("HK LockMechanism added (id: %x)", dl.hkAccessory.Id)
+	return dl.hkAccessory
+}
+
+// GetHkState returns lock state in HomeKit characteristic format,
+// open door and output switched on (also from CLU side, ex. wall button) are reported as unsecured
+func (dl *DoorLock) GetHkState() int {
+	if dl.unlocked || dl.State || !dl.Closed {
+		return characteristic.LockCurrentStateUnsecured
+	}
+	return characteristic.LockCurrentStateSecured
+}
+
+// Sync sets HK accessory values based on DoorLock values
+func (dl *DoorLock) Sync() {
+	dl.hkService.LockCurrentState.SetValue(dl.GetHkState())
+	if dl.unlocked || dl.State {
+		dl.hkService.LockTargetState.SetValue(characteristic.LockTargetStateUnsecured)
+	} else {
+		dl.hkService.LockTargetState.SetValue(characteristic.LockTargetStateSecured)
+	}
+}
+
+// SetTarget unlocks the strike for UnlockTime, securing is done automatically
+func (dl *DoorLock) SetTarget(target int) {
+	if target != characteristic.LockTargetStateUnsecured {
+		// strike can not be locked on demand, it is secured when output goes off
+		dl.block.Lock()
+		dl.Sync()
+		dl.block.Unlock()
+		return
+	}
+
+	dl.block.Lock()
+	dl.unlocked = true
+	dl.State = true
+	dl.Sync()
+
+	if dl.relockTimer != nil {
+		dl.relockTimer.Stop()
+	}
+	dl.relockTimer = time.AfterFunc(time.Duration(dl.UnlockTime)*time.Second, dl.relock)
+	// copy of sent values, request is marshalled later by GateBroker goroutine
+	req := dl.Req
+	req.DoorLock = &DoorLock{
+		CluObject:  CluObject{Id: dl.Id, Name: dl.Name, Kind: dl.Kind},
+		State:      dl.State,
+		UnlockTime: dl.UnlockTime,
+	}
+	dl.block.Unlock()
+
+	_, err := dl.SendReq(req)
+	if err != nil {
+		dl.clu.set.Error(fmt.Errorf("DoorLock SetTarget: %w", err))
+	}
+}
+
+// relock is called after UnlockTime, output is already switched off by Grenton
+func (dl *DoorLock) relock() {
+	dl.block.Lock()
+	defer dl.block.Unlock()
+
+	dl.unlocked = false
+	dl.State = false
+	dl.Sync()
+}
+
+// LoadReqObject checks object received from http request end reads it into DoorLock
+func (dl *DoorLock) LoadReqObject(obj ReqObject) error {
+	if obj.Kind != "DoorLock" {
+		return fmt.Errorf("DoorLock LoadReqObject: wrong object kind (%s)", obj.Kind)
+	}
+
+	if obj.DoorLock == nil {
+		return fmt.Errorf("DoorLock LoadReqObject: missing DoorLock object")
+	}
+
+	dl.clu.set.Debugf("DoorLock LoadReqObject loading: \n%+v", obj)
+
+	dl.SetFault(false)
+
+	dl.block.Lock()
+	defer dl.block.Unlock()
+
+	dl.State = obj.DoorLock.State
+	if dl.Input != "" {
+		dl.Closed = obj.DoorLock.Closed
+	}
+	dl.Sync()
+
+	return nil
+}
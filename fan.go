@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+	"github.com/brutella/hap/service"
+)
+
+// Fan is a ventilation unit driven by Grenton output, by default 0-10V analog output is assumed
+type Fan struct {
+	CluObject
+
+	// Levels is a config option, set of discrete output values (first one means off) used instead of 0-10V
+	Levels []float64 `json:",omitempty"`
+	// Auto is a config option, name of user variable enabling automatic mode
+	Auto string `json:",omitempty"`
+
+	Value    float64
+	AutoMode bool
+
+	active    bool
+	speed     float64
+	lastSpeed float64
+	block     sync.Mutex
+
+	hkAccessory *accessory.A
+	hkService   *service.FanV2
+	hkSpeed     *characteristic.RotationSpeed
+	hkCurrent   *characteristic.CurrentFanState
+	hkTarget    *characteristic.TargetFanState
+}
+
+func (gf *Fan) InitAll() {
+	gf.Req = ReqObject{
+		Kind:   "Fan",
+		Clu:    gf.clu.Id,
+		Id:     gf.GetMixedId(),
+		Source: gf.Auto,
+	}
+	gf.lastSpeed = 100
+
+	gf.AppendHk()
+}
+
+func (gf *Fan) GetA() *accessory.A {
+	return gf.hkAccessory
+}
+
+func (gf *Fan) AppendHk() *accessory.A {
+	info := accessory.Info{
+		Name:         gf.Name,
+		SerialNumber: fmt.Sprintf("%d", gf.Id),
+		Manufacturer: "Grenton",
+		Model:        gf.Kind,
+	}
+
+	gf.hkAccessory = accessory.New(info, accessory.TypeFan)
+	gf.hkAccessory.Id = gf.GetLongId()
+
+	gf.hkService = service.NewFanV2()
+	gf.hkAccessory.AddS(gf.hkService.S)
+
+	gf.hkSpeed = characteristic.NewRotationSpeed()
+	if len(gf.Levels) > 1 {
+		gf.hkSpeed.SetStepValue(100 / float64(len(gf.Levels)-1))
+	}
+	gf.hkService.AddC(gf.hkSpeed.C)
+
+	gf.hkCurrent = characteristic.NewCurrentFanState()
+	gf.hkService.AddC(gf.hkCurrent.C)
+
+	if gf.Auto != "" {
+		gf.hkTarget = characteristic.NewTargetFanState()
+		gf.hkService.AddC(gf.hkTarget.C)
+		gf.hkTarget.OnValueRemoteUpdate(gf.SetTargetState)
+		gf.guardWrites(gf.hkTarget.C)
+	}
+
+	gf.hkService.Active.OnValueRemoteUpdate(gf.SetActive)
+	gf.hkSpeed.OnValueRemoteUpdate(gf.SetSpeed)
+	gf.guardWrites(gf.hkService.Active.C, gf.hkSpeed.C)
+
+	gf.clu.set.Logf("HK Fan added (id: %x)", gf.hkAccessory.Id)
+	return gf.hkAccessory
+}
+
+// Sync sets HK accessory values based on Fan values
+func (gf *Fan) Sync() {
+	if gf.active {
+		gf.hkService.Active.SetValue(characteristic.ActiveActive)
+		gf.hkSpeed.SetValue(gf.speed)
+		gf.hkCurrent.SetValue(characteristic.CurrentFanStateBlowingAir)
+	} else {
+		gf.hkService.Active.SetValue(characteristic.ActiveInactive)
+		gf.hkCurrent.SetValue(characteristic.CurrentFanStateInactive)
+	}
+
+	if gf.hkTarget != nil {
+		if gf.AutoMode {
+			gf.hkTarget.SetValue(characteristic.TargetFanStateAuto)
+		} else {
+			gf.hkTarget.SetValue(characteristic.TargetFanStateManual)
+		}
+	}
+}
+
+// SetActive switches fan on (restoring last speed) or off
+func (gf *Fan) SetActive(active int) {
+	gf.block.Lock()
+	gf.active = active == characteristic.ActiveActive
+	if gf.active {
+		gf.speed = gf.lastSpeed
+	}
+	gf.block.Unlock()
+
+	gf.send()
+}
+
+// SetSpeed sets rotation speed in percents, 0 switches fan off
+func (gf *Fan) SetSpeed(speed float64) {
+	gf.block.Lock()
+	gf.speed = speed
+	gf.active = speed > 0
+	if gf.active {
+		gf.lastSpeed = speed
+	}
+	gf.block.Unlock()
+
+	gf.send()
+}
+
+// SetTargetState switches between manual and automatic mode
+func (gf *Fan) SetTargetState(state int) {
+	gf.block.Lock()
+	gf.AutoMode = state == characteristic.TargetFanStateAuto
+	gf.block.Unlock()
+
+	gf.send()
+}
+
+// levelIndex returns index of configured level matching rotation speed
+func (gf *Fan) levelIndex(speed float64) int {
+	steps := float64(len(gf.Levels) - 1)
+	ix := int(math.Round(math.Max(0, math.Min(speed, 100)) / 100 * steps))
+	// any speed above 0 means fan is active, so first level (off) is skipped
+	if ix == 0 && speed > 0 {
+		ix = 1
+	}
+	return ix
+}
+
+// valueFromSpeed converts rotation speed to Grenton output value
+func (gf *Fan) valueFromSpeed() float64 {
+	speed := gf.speed
+	if !gf.active {
+		speed = 0
+	}
+
+	if len(gf.Levels) > 1 {
+		return gf.Levels[gf.levelIndex(speed)]
+	}
+	if len(gf.Levels) == 1 {
+		return gf.Levels[0]
+	}
+	return math.Max(0, math.Min(speed, 100)) / 10
+}
+
+// speedFromValue converts Grenton output value to rotation speed, nearest level is used for discrete levels
+func (gf *Fan) speedFromValue(value float64) float64 {
+	if len(gf.Levels) > 1 {
+		nearest := 0
+		for ix, level := range gf.Levels {
+			if math.Abs(level-value) < math.Abs(gf.Levels[nearest]-value) {
+				nearest = ix
+			}
+		}
+		return float64(nearest) * 100 / float64(len(gf.Levels)-1)
+	}
+	return math.Max(0, math.Min(value*10, 100))
+}
+
+func (gf *Fan) send() {
+	gf.block.Lock()
+	gf.Value = gf.valueFromSpeed()
+	// copy of sent values, request is marshalled later by GateBroker goroutine
+	req := gf.Req
+	req.Fan = &Fan{
+		CluObject: CluObject{Id: gf.Id, Name: gf.Name, Kind: gf.Kind},
+		Auto:      gf.Auto,
+		AutoMode:  gf.AutoMode,
+		Value:     gf.Value,
+	}
+	gf.block.Unlock()
+
+	_, err := gf.SendReq(req)
+
+	if err != nil {
+		gf.clu.set.Error(fmt.Errorf("Fan send: %w", err))
+	}
+}
+
+// LoadReqObject checks object received from http request end reads it into Fan
+func (gf *Fan) LoadReqObject(obj ReqObject) error {
+	if obj.Kind != "Fan" {
+		return fmt.Errorf("Fan LoadReqObject: wrong object kind (%s)", obj.Kind)
+	}
+
+	if obj.Fan == nil {
+		return fmt.Errorf("Fan LoadReqObject: missing Fan object")
+	}
+
+	gf.clu.set.Debugf("Fan LoadReqObject loading: \n%+v", obj)
+
+	gf.SetFault(false)
+
+	gf.block.Lock()
+	defer gf.block.Unlock()
+
+	gf.Value = obj.Fan.Value
+	gf.AutoMode = obj.Fan.AutoMode
+
+	speed := gf.speedFromValue(gf.Value)
+	gf.active = speed > 0
+	if gf.active {
+		gf.speed = speed
+		gf.lastSpeed = speed
+	}
+	gf.Sync()
+
+	return nil
+}
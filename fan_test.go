@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestFanValueFromSpeed(t *testing.T) {
+	tests := []struct {
+		name   string
+		levels []float64
+		active bool
+		speed  float64
+		want   float64
+	}{
+		{"analog off", nil, false, 50, 0},
+		{"analog half", nil, true, 50, 5},
+		{"analog full", nil, true, 100, 10},
+		{"analog above range", nil, true, 120, 10},
+		{"levels off", []float64{0, 1, 2, 3}, false, 100, 0},
+		{"levels first step", []float64{0, 1, 2, 3}, true, 100.0 / 3, 1},
+		{"levels second step", []float64{0, 1, 2, 3}, true, 200.0 / 3, 2},
+		{"levels full", []float64{0, 1, 2, 3}, true, 100, 3},
+		{"levels rounded to nearest step", []float64{0, 1, 2, 3}, true, 60, 2},
+		{"levels low speed skips off", []float64{0, 1, 2, 3}, true, 1, 1},
+		{"two levels", []float64{0, 7}, true, 30, 7},
+		{"single level", []float64{4}, true, 50, 4},
+		{"single level off", []float64{4}, false, 0, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gf := &Fan{Levels: tt.levels, active: tt.active, speed: tt.speed}
+			if got := gf.valueFromSpeed(); got != tt.want {
+				t.Errorf("valueFromSpeed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFanSpeedFromValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		levels []float64
+		value  float64
+		want   float64
+	}{
+		{"analog off", nil, 0, 0},
+		{"analog half", nil, 5, 50},
+		{"analog above range", nil, 12, 100},
+		{"analog below range", nil, -1, 0},
+		{"levels off", []float64{0, 1, 2, 3}, 0, 0},
+		{"levels step", []float64{0, 1, 2, 3}, 2, 200.0 / 3},
+		{"levels full", []float64{0, 1, 2, 3}, 3, 100},
+		{"levels nearest", []float64{0, 1, 2, 3}, 2.4, 200.0 / 3},
+		{"unordered levels", []float64{0, 10, 5}, 5, 100},
+		{"two levels", []float64{0, 7}, 7, 100},
+		{"single level uses analog range", []float64{4}, 4, 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gf := &Fan{Levels: tt.levels}
+			if got := gf.speedFromValue(tt.value); got != tt.want {
+				t.Errorf("speedFromValue(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFanLevelsRoundTrip(t *testing.T) {
+	gf := &Fan{Levels: []float64{0, 2, 5, 8, 10}, active: true}
+	for ix, level := range gf.Levels[1:] {
+		gf.speed = gf.speedFromValue(level)
+		if got := gf.valueFromSpeed(); got != level {
+			t.Errorf("level %d: value %v converted to speed %v and back to %v", ix+1, level, gf.speed, got)
+		}
+	}
+}
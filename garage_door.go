@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+)
+
+const defaultGarageDoorOpenTime = 20
+
+// GarageDoor is a gate or garage door driven by Grenton relay (Id) with optional position input
+type GarageDoor struct {
+	CluObject
+
+	// Input is a config option, mixed id (ex. DIN0012) of limit switch active when door is closed
+	Input string `json:",omitempty"`
+	// Pulse is a config option, relay is pulsed for PulseTime (ms) instead of switched on (open) and off (close)
+	Pulse     bool `json:",omitempty"`
+	PulseTime int  `json:",omitempty"`
+	// OpenTime is a config option, time in seconds needed to fully open or close the door
+	OpenTime int `json:",omitempty"`
+
+	State  bool
+	Closed bool
+
+	currentState int
+	targetState  int
+	moving       bool
+	moveTimer    *time.Timer
+	block        sync.Mutex
+
+	hk *accessory.GarageDoorOpener
+}
+
+func (gd *GarageDoor) InitAll() {
+	gd.Req = ReqObject{
+		Kind:   "GarageDoor",
+		Clu:    gd.clu.Id,
+		Id:     gd.GetMixedId(),
+		Source: gd.Input,
+	}
+
+	if gd.OpenTime <= 0 {
+		gd.OpenTime = defaultGarageDoorOpenTime
+	}
+	if gd.Pulse && gd.PulseTime <= 0 {
+		gd.PulseTime = 500
+	}
+
+	gd.currentState = characteristic.CurrentDoorStateClosed
+	gd.targetState = characteristic.TargetDoorStateClosed
+
+	gd.AppendHk()
+}
+
+func (gd *GarageDoor) AppendHk() *accessory.GarageDoorOpener {
+	info := accessory.Info{
+		Name:         gd.Name,
+		SerialNumber: fmt.Sprintf("%d", gd.Id),
+		Manufacturer: "Grenton",
+		Model:        gd.Kind,
+	}
+
+	gd.hk = accessory.NewGarageDoorOpener(info)
+	gd.hk.Id = gd.GetLongId()
+
+	gd.hk.GarageDoorOpener.CurrentDoorState.SetValue(gd.currentState)
+	gd.hk.GarageDoorOpener.TargetDoorState.SetValue(gd.targetState)
+	gd.hk.GarageDoorOpener.ObstructionDetected.SetValue(false)
+
+	gd.hk.GarageDoorOpener.TargetDoorState.OnValueRemoteUpdate(gd.SetTarget)
+	gd.guardWrites(gd.hk.GarageDoorOpener.TargetDoorState.C)
+
+	gd.clu.set.Logf("HK GarageDoorOpener added (id: %x)", gd.hk.A.Id)
+	return gd.hk
+}
+
+// Sync sets HK accessory values based on GarageDoor values
+func (gd *GarageDoor) Sync() {
+	gd.hk.GarageDoorOpener.CurrentDoorState.SetValue(gd.currentState)
+	gd.hk.GarageDoorOpener.TargetDoorState.SetValue(gd.targetState)
+}
+
+// SetTarget sends command to relay and starts timer waiting for door to reach target state
+func (gd *GarageDoor) SetTarget(target int) {
+	if !gd.startMove(target) {
+		return
+	}
+
+	_, err := gd.SendReq(gd.snapshot())
+	if err != nil {
+		gd.clu.set.Error(fmt.Errorf("GarageDoor SetTarget: %w", err))
+	}
+}
+
+// snapshot returns request with copy of values sent to GATE, request is marshalled later by GateBroker goroutine
+func (gd *GarageDoor) snapshot() ReqObject {
+	gd.block.Lock()
+	defer gd.block.Unlock()
+
+	req := gd.Req
+	req.GarageDoor = &GarageDoor{
+		CluObject: CluObject{Id: gd.Id, Name: gd.Name, Kind: gd.Kind},
+		Pulse:     gd.Pulse,
+		PulseTime: gd.PulseTime,
+		State:     gd.State,
+	}
+	return req
+}
+
+// startMove sets moving state and (re)starts the timer, returns false if door is already in target state
+func (gd *GarageDoor) startMove(target int) bool {
+	gd.block.Lock()
+	defer gd.block.Unlock()
+
+	gd.clu.set.Debugf("GarageDoor SetTarget | target: %d\tcurrent: %d\n", target, gd.currentState)
+
+	gd.targetState = target
+	// CurrentDoorState open and closed values are equal to TargetDoorState ones
+	if !gd.moving && gd.currentState == target {
+		gd.Sync()
+		return false
+	}
+
+	if target == characteristic.TargetDoorStateOpen {
+		gd.State = true
+		gd.currentState = characteristic.CurrentDoorStateOpening
+	} else {
+		gd.State = false
+		gd.currentState = characteristic.CurrentDoorStateClosing
+	}
+	gd.hk.GarageDoorOpener.ObstructionDetected.SetValue(false)
+	gd.Sync()
+
+	if gd.moveTimer != nil {
+		gd.moveTimer.Stop()
+	}
+	gd.moving = true
+	gd.moveTimer = time.AfterFunc(time.Duration(gd.OpenTime)*time.Second, gd.moveFinished)
+
+	return true
+}
+
+// reached checks if door position input confirms target state, without input it is always true
+func (gd *GarageDoor) reached() bool {
+	if gd.Input == "" {
+		return true
+	}
+	return gd.Closed == (gd.targetState == characteristic.TargetDoorStateClosed)
+}
+
+// moveFinished is called after OpenTime, reports obstruction when position input does not match target
+func (gd *GarageDoor) moveFinished() {
+	gd.block.Lock()
+	defer gd.block.Unlock()
+
+	gd.moving = false
+
+	if !gd.reached() {
+		gd.clu.set.Logf("GarageDoor %s: target state not reached in %ds, obstruction detected", gd.Name, gd.OpenTime)
+		gd.currentState = characteristic.CurrentDoorStateStopped
+		gd.hk.GarageDoorOpener.ObstructionDetected.SetValue(true)
+		gd.Sync()
+		return
+	}
+
+	gd.currentState = gd.targetState
+	gd.Sync()
+}
+
+// followRelay sets door state from relay state when there is no position input,
+// so changes made outside HomeKit are shown (pulse relay does not tell door position)
+func (gd *GarageDoor) followRelay() {
+	if gd.Pulse || gd.moving {
+		return
+	}
+
+	if gd.State {
+		gd.currentState = characteristic.CurrentDoorStateOpen
+		gd.targetState = characteristic.TargetDoorStateOpen
+	} else {
+		gd.currentState = characteristic.CurrentDoorStateClosed
+		gd.targetState = characteristic.TargetDoorStateClosed
+	}
+	gd.Sync()
+}
+
+// LoadReqObject checks object received from http request end reads it into GarageDoor
+func (gd *GarageDoor) LoadReqObject(obj ReqObject) error {
+	if obj.Kind != "GarageDoor" {
+		return fmt.Errorf("GarageDoor LoadReqObject: wrong object kind (%s)", obj.Kind)
+	}
+
+	if obj.GarageDoor == nil {
+		return fmt.Errorf("GarageDoor LoadReqObject: missing GarageDoor object")
+	}
+
+	gd.clu.set.Debugf("GarageDoor LoadReqObject loading: \n%+v", obj)
+
+	gd.SetFault(false)
+
+	gd.block.Lock()
+	defer gd.block.Unlock()
+
+	gd.State = obj.GarageDoor.State
+	if gd.Input == "" {
+		gd.followRelay()
+		return nil
+	}
+	gd.Closed = obj.GarageDoor.Closed
+
+	if gd.moving {
+		// door can be closed earlier than expected
+		if gd.targetState == characteristic.TargetDoorStateClosed && gd.Closed {
+			gd.moveTimer.Stop()
+			gd.moving = false
+			gd.currentState = characteristic.CurrentDoorStateClosed
+			gd.Sync()
+		}
+		return nil
+	}
+
+	if gd.Closed {
+		gd.currentState = characteristic.CurrentDoorStateClosed
+		gd.targetState = characteristic.TargetDoorStateClosed
+	} else {
+		gd.currentState = characteristic.CurrentDoorStateOpen
+		gd.targetState = characteristic.TargetDoorStateOpen
+	}
+	gd.hk.GarageDoorOpener.ObstructionDetected.SetValue(false)
+	gd.Sync()
+
+	return nil
+}
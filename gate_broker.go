@@ -2,130 +2,321 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/rand/v2"
 	"net/http"
-	"sync"
 	"time"
 )
 
-const httpReadTimeout = 10 * time.Second
+const (
+	httpReadTimeout = 10 * time.Second
+	// drainTimeout limits sending of already queued objects on shutdown
+	drainTimeout = 5 * time.Second
+	// gateRequestTimeout limits waiting for GATE response in HomeKit callbacks
+	gateRequestTimeout = 20 * time.Second
+	// defaultBatchTimeout limits sending of one batch with retries, queue is blocked meanwhile,
+	// so it is well below gateRequestTimeout and callers queued behind slow batch still get their turn
+	defaultBatchTimeout = httpReadTimeout
+)
+
+var errBrokerStopped = errors.New("GateBroker stopped")
 
+// GateBroker collects objects from many callers and sends them to GATE in batches (up to MaxQueueLength).
+// Queue is owned by Run goroutine, callers use Do and get their own result.
 type GateBroker struct {
 	FlushPeriod    time.Duration
 	MaxQueueLength int
 	PostPath       string
+	// Retries is a number of repeated attempts of failed request, use only for idempotent (read) requests
+	Retries int
+	// RetryDelay is a delay before first retry, it is doubled with every next attempt
+	RetryDelay time.Duration
+	// BatchTimeout limits total time of sending one batch, including retries
+	BatchTimeout time.Duration
+
+	breaker *circuitBreaker
 
-	queue      []ReqObject
-	cErrors    []chan error
-	u          updater
-	working    sync.Mutex
-	requesting sync.Mutex
+	requests chan *brokerRequest
+	done     chan struct{}
+	queue    []*queuedObject
+	u        updater
 }
 
 type updater interface {
 	update([]ReqObject)
+	fault([]ReqObject, error)
 	Logf(string, ...interface{})
 	Debugf(string, ...interface{})
 }
 
-func (gb *GateBroker) Init(u updater, maxLength int, flushPeriod time.Duration) {
-	gb.u = u
-	gb.MaxQueueLength = maxLength
-	gb.FlushPeriod = flushPeriod
+// brokerRequest is a set of objects queued by one caller, result is sent when all of them are flushed
+type brokerRequest struct {
+	ctx     context.Context
+	objects []ReqObject
+	left    int
+	data    []ReqObject
+	err     error
+	result  chan brokerResult
 }
 
-func (gb *GateBroker) checkIfPresent(obj ReqObject) bool {
-	if len(gb.queue) == 0 {
-		return false
-	}
-
-	for _, q := range gb.queue {
-		if obj.Equal(q) {
-			return true
-		}
-	}
+type brokerResult struct {
+	data []ReqObject
+	err  error
+}
 
-	return false
+// queuedObject is an object waiting for flush, the same object can be requested by many callers
+type queuedObject struct {
+	obj     ReqObject
+	waiting []*brokerRequest
 }
 
-func (gb *GateBroker) Queue(cErr chan error, objects ...ReqObject) (objectsLeft []ReqObject) {
-	gb.working.Lock()
-	defer gb.working.Unlock()
+// Init prepares GateBroker, maxLength below 1 is treated as 1 (every object is sent separately)
+func (gb *GateBroker) Init(u updater, maxLength int, flushPeriod time.Duration, breaker *circuitBreaker) {
+	gb.u = u
+	gb.MaxQueueLength = max(maxLength, 1)
+	gb.FlushPeriod = flushPeriod
+	gb.BatchTimeout = defaultBatchTimeout
+	gb.breaker = breaker
+
+	gb.requests = make(chan *brokerRequest)
+	gb.done = make(chan struct{})
+}
 
+// Do queues objects and waits until all of them are sent, returns objects received from GATE for them.
+// Objects not sent yet are dropped when ctx is done.
+func (gb *GateBroker) Do(ctx context.Context, objects ...ReqObject) ([]ReqObject, error) {
 	if len(objects) == 0 {
-		return
+		return nil, nil
+	}
+
+	br := newBrokerRequest(ctx, objects...)
+
+	select {
+	case gb.requests <- br:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-gb.done:
+		return nil, errBrokerStopped
 	}
 
-	for gb.spaceLeft() == 0 {
-		time.Sleep(10 * time.Millisecond)
+	select {
+	case res := <-br.result:
+		return res.data, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
+}
 
-	if cErr != nil {
-		gb.cErrors = append(gb.cErrors, cErr)
+func newBrokerRequest(ctx context.Context, objects ...ReqObject) *brokerRequest {
+	return &brokerRequest{
+		ctx:     ctx,
+		objects: objects,
+		left:    len(objects),
+		result:  make(chan brokerResult, 1),
 	}
+}
 
-	gb.requesting.Lock()
-	defer gb.requesting.Unlock()
+// Done is closed when Run has finished
+func (gb *GateBroker) Done() <-chan struct{} {
+	return gb.done
+}
 
-	emptyQueue := (len(gb.queue) == 0)
-	objectsLeft = []ReqObject{}
+// Run sends queued objects when queue is full or after FlushPeriod.
+// When ctx is done, objects already queued are sent and Run returns.
+func (gb *GateBroker) Run(ctx context.Context) {
+	defer close(gb.done)
 
-	for _, obj := range objects {
-		if !gb.checkIfPresent(obj) {
-			if gb.spaceLeft() > 0 {
-				gb.queue = append(gb.queue, obj)
-			} else {
-				objectsLeft = append(objectsLeft, obj)
+	var flushTimer <-chan time.Time
+	for {
+		select {
+		case br := <-gb.requests:
+			gb.enqueue(br)
+			for len(gb.queue) >= gb.MaxQueueLength {
+				gb.Flush(ctx)
 			}
+			if len(gb.queue) > 0 && flushTimer == nil {
+				flushTimer = time.After(gb.FlushPeriod)
+			}
+		case <-flushTimer:
+			flushTimer = nil
+			for len(gb.queue) > 0 {
+				gb.Flush(ctx)
+			}
+		case <-ctx.Done():
+			gb.u.Logf("GateBroker: stopping, draining %d queued objects", len(gb.queue))
+			drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), drainTimeout)
+			for len(gb.queue) > 0 {
+				gb.Flush(drainCtx)
+			}
+			cancel()
+			return
 		}
 	}
+}
 
-	if gb.spaceLeft() == 0 {
-		go gb.Flush()
-	} else {
-		if !emptyQueue {
-			time.AfterFunc(gb.FlushPeriod, gb.Flush)
+// enqueue adds request objects to the queue, object already queued is replaced by the newer one
+func (gb *GateBroker) enqueue(br *brokerRequest) {
+	for _, obj := range br.objects {
+		found := false
+		for _, q := range gb.queue {
+			if obj.Equal(q.obj) {
+				q.obj = obj
+				q.waiting = append(q.waiting, br)
+				found = true
+				break
+			}
+		}
+		if !found {
+			gb.queue = append(gb.queue, &queuedObject{obj: obj, waiting: []*brokerRequest{br}})
 		}
 	}
-	return
 }
 
-func (gb *GateBroker) emptyQueue() {
-	gb.queue = []ReqObject{}
-	gb.cErrors = []chan error{}
+// cancelled returns true when every caller waiting for the object gave up
+func (q *queuedObject) cancelled() bool {
+	for _, br := range q.waiting {
+		if br.ctx.Err() == nil {
+			return false
+		}
+	}
+	return true
 }
 
-func (gb *GateBroker) flushErrors(err error) {
-	for _, ce := range gb.cErrors {
-		ce <- err
+// complete passes flush result to waiting callers, caller gets result when all its objects are flushed
+func (q *queuedObject) complete(data []ReqObject, err error) {
+	for _, br := range q.waiting {
+		br.left--
+		if err != nil {
+			br.err = err
+		}
+		for _, obj := range data {
+			if obj.SameObject(q.obj) {
+				br.data = append(br.data, obj)
+			}
+		}
+
+		if br.left == 0 {
+			br.result <- brokerResult{data: br.data, err: br.err}
+		}
 	}
 }
 
-func (gb *GateBroker) Flush() {
-	defer gb.emptyQueue()
-	gb.requesting.Lock()
-	defer gb.requesting.Unlock()
+// Flush sends up to MaxQueueLength objects from the queue, objects of cancelled requests are skipped
+func (gb *GateBroker) Flush(ctx context.Context) {
+	n := min(len(gb.queue), gb.MaxQueueLength)
+	batch := gb.queue[:n]
+	gb.queue = gb.queue[n:]
+
+	sent := []*queuedObject{}
+	objects := []ReqObject{}
+	for _, q := range batch {
+		if q.cancelled() {
+			q.complete(nil, context.Canceled)
+			continue
+		}
+		sent = append(sent, q)
+		objects = append(objects, q.obj)
+	}
 
-	if len(gb.queue) == 0 {
-		gb.u.Logf("]![ GateBroker tried to flush on empty queue! Skipping!\n")
+	if len(objects) == 0 {
 		return
 	}
 
+	ctx, cancel := batchContext(ctx, sent, gb.BatchTimeout)
+	defer cancel()
+
+	data, err := gb.send(ctx, objects)
+	if err != nil {
+		gb.u.fault(objects, err)
+	} else {
+		gb.u.Logf("GateBroker Flush finished, will update.")
+		gb.u.update(data)
+	}
+
+	for _, q := range sent {
+		q.complete(data, err)
+	}
+}
+
+// batchContext returns context cancelled after timeout, or when every caller waiting for the batch gave up,
+// so request and its retries are not continued when nobody waits for the result
+func batchContext(ctx context.Context, batch []*queuedObject, timeout time.Duration) (context.Context, context.CancelFunc) {
+	waiting := []context.Context{}
+	for _, q := range batch {
+		for _, br := range q.waiting {
+			waiting = append(waiting, br.ctx)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	go func() {
+		for _, wctx := range waiting {
+			select {
+			case <-wctx.Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
+
+// send posts objects to GATE, failed requests are retried and reported to circuit breaker
+func (gb *GateBroker) send(ctx context.Context, objects []ReqObject) ([]ReqObject, error) {
+	if !gb.breaker.Allow() {
+		gb.u.Debugf("GateBroker Flush: circuit breaker is open, %d objects rejected", len(objects))
+		return nil, errGateUnavailable
+	}
+
 	var jsonQ []byte
 	if gb.MaxQueueLength > 1 {
-		jsonQ, _ = json.Marshal(gb.queue)
+		jsonQ, _ = json.Marshal(objects)
 	} else {
-		jsonQ, _ = json.Marshal(gb.queue[0])
+		jsonQ, _ = json.Marshal(objects[0])
 	}
-	gb.u.Logf("GateBroker Flush: query prepared, count: %d, bytes: %d", len(gb.queue), len(jsonQ))
+	gb.u.Logf("GateBroker Flush: query prepared, count: %d, bytes: %d", len(objects), len(jsonQ))
 	gb.u.Debugf("GateBroker Flush: json query:\n%s\n", jsonQ)
-	req, err := http.NewRequest("POST", gb.PostPath, bytes.NewBuffer(jsonQ))
+
+	data, err := gb.post(ctx, jsonQ)
+	// probe request is not retried, breaker is opened again on failure
+	for attempt := 0; err != nil && attempt < gb.Retries && gb.breaker.State() == breakerClosed; attempt++ {
+		delay := gb.backoff(attempt)
+		gb.u.Logf("GateBroker Flush: request failed, retry %d/%d in %s", attempt+1, gb.Retries, delay)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		data, err = gb.post(ctx, jsonQ)
+	}
+
+	if err != nil {
+		gb.breaker.Failure()
+		return nil, err
+	}
+	gb.breaker.Success()
+
+	return data, nil
+}
+
+// backoff returns exponential delay for retry attempt, jittered between half and full value
+func (gb *GateBroker) backoff(attempt int) time.Duration {
+	delay := gb.RetryDelay << attempt
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// post sends json query to GATE and returns received objects
+func (gb *GateBroker) post(ctx context.Context, jsonQ []byte) ([]ReqObject, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", gb.PostPath, bytes.NewBuffer(jsonQ))
 	if err != nil {
-		gb.flushErrors(err)
 		gb.u.Logf("New POST reques failed: ", err)
-		return
+		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -135,35 +326,32 @@ func (gb *GateBroker) Flush() {
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		gb.flushErrors(err)
 		gb.u.Logf("GateBroker RequestAndUpdate http Client failed:\n%v", err)
-		return
+		return nil, err
 	}
 
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 || resp.StatusCode < 200 {
-		gb.flushErrors(fmt.Errorf("GateBroker received non-success http response from grenton host: %s", resp.Status))
 		gb.u.Logf("GateBroker received non-success http response from grenton host: ", resp.Status)
-		return
+		return nil, fmt.Errorf("GateBroker received non-success http response from grenton host: %s", resp.Status)
 	}
 
-	data := []ReqObject{}
-
 	bodyBytes, _ := ioutil.ReadAll(resp.Body)
 	bodyString := string(bodyBytes)
 	gb.u.Debugf("GrentonSet RequestAndUpdate: received body:\n%s\n", bodyString)
 
-	err = json.Unmarshal(bodyBytes, &data)
+	// single object is sent and received when batching is off (setter)
+	data := []ReqObject{}
+	if gb.MaxQueueLength > 1 {
+		err = json.Unmarshal(bodyBytes, &data)
+	} else {
+		obj := ReqObject{}
+		err = json.Unmarshal(bodyBytes, &obj)
+		data = append(data, obj)
+	}
 	if err != nil {
-		gb.flushErrors(err)
 		gb.u.Logf("Unmarshal data error: ", err)
-		return
+		return nil, err
 	}
-	gb.u.Logf("GateBroker Flush finished, will update.")
-	gb.u.update(data)
-
-}
-
-func (gb *GateBroker) spaceLeft() int {
-	return gb.MaxQueueLength - len(gb.queue)
+	return data, nil
 }
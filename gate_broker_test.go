@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testUpdater struct {
+	mu      sync.Mutex
+	updated []ReqObject
+	faults  []ReqObject
+}
+
+func (tu *testUpdater) update(objects []ReqObject) {
+	tu.mu.Lock()
+	defer tu.mu.Unlock()
+	tu.updated = append(tu.updated, objects...)
+}
+
+func (tu *testUpdater) fault(objects []ReqObject, err error) {
+	tu.mu.Lock()
+	defer tu.mu.Unlock()
+	tu.faults = append(tu.faults, objects...)
+}
+
+func (tu *testUpdater) faultCount() int {
+	tu.mu.Lock()
+	defer tu.mu.Unlock()
+	return len(tu.faults)
+}
+
+func (tu *testUpdater) Logf(string, ...interface{})   {}
+func (tu *testUpdater) Debugf(string, ...interface{}) {}
+
+// newTestGate returns GATE stub answering with received object (as update-script does) or with given status
+func newTestGate(t *testing.T, status int) (*httptest.Server, func() []ReqObject) {
+	var mu sync.Mutex
+	posted := []ReqObject{}
+
+	gate := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		obj := ReqObject{}
+		json.NewDecoder(r.Body).Decode(&obj)
+
+		mu.Lock()
+		posted = append(posted, obj)
+		mu.Unlock()
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		obj.Cmd = ""
+		json.NewEncoder(w).Encode(obj)
+	}))
+	t.Cleanup(gate.Close)
+
+	return gate, func() []ReqObject {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]ReqObject{}, posted...)
+	}
+}
+
+func newTestSetter(u updater, postPath string) *GateBroker {
+	gb := &GateBroker{}
+	gb.Init(u, 1, 200*time.Millisecond, &circuitBreaker{Threshold: 100, ProbePeriod: time.Minute})
+	gb.PostPath = postPath
+	return gb
+}
+
+func TestGateBrokerDrainsQueueOnShutdown(t *testing.T) {
+	gate, posted := newTestGate(t, http.StatusOK)
+	gb := newTestSetter(&testUpdater{}, gate.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	gs := &GrentonSet{ctx: ctx}
+
+	// write is queued with the same context as SendReq uses, then grengate is stopping
+	reqCtx, reqCancel := gs.requestContext(gateRequestTimeout)
+	defer reqCancel()
+	write := ReqObject{Clu: "CLU_test", Id: "DOU0001", Kind: "Light", Cmd: "SET"}
+	br := newBrokerRequest(reqCtx, write)
+	gb.enqueue(br)
+	cancel()
+
+	gb.Run(ctx)
+
+	if got := posted(); len(got) != 1 || !got[0].Equal(write) {
+		t.Fatalf("posted = %+v, want queued write", got)
+	}
+
+	res := <-br.result
+	if res.err != nil {
+		t.Fatalf("result error = %v, want nil", res.err)
+	}
+	if len(res.data) != 1 || !res.data[0].SameObject(write) {
+		t.Fatalf("result data = %+v, want object received from GATE", res.data)
+	}
+}
+
+func TestGateBrokerStopsRetriesWhenCallersGaveUp(t *testing.T) {
+	gate, _ := newTestGate(t, http.StatusInternalServerError)
+	u := &testUpdater{}
+	gb := newTestSetter(u, gate.URL)
+	gb.Retries = 5
+	gb.RetryDelay = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		<-gb.Done()
+	}()
+	go gb.Run(ctx)
+
+	reqCtx, reqCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer reqCancel()
+	_, err := gb.Do(reqCtx, ReqObject{Clu: "CLU_test", Id: "DOU0001", Kind: "Light", Cmd: "SET"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Do error = %v, want deadline exceeded", err)
+	}
+
+	// broker waits for retry an hour unless batch is abandoned
+	deadline := time.Now().Add(time.Second)
+	for u.faultCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if u.faultCount() != 1 {
+		t.Fatalf("flush of abandoned batch not finished, faults reported: %d", u.faultCount())
+	}
+}
+
+func TestGateBrokerClampsQueueLength(t *testing.T) {
+	for _, maxLength := range []int{-1, 0} {
+		gb := &GateBroker{}
+		gb.Init(&testUpdater{}, maxLength, time.Second, &circuitBreaker{Threshold: 1})
+		if gb.MaxQueueLength != 1 {
+			t.Errorf("Init(maxLength: %d) MaxQueueLength = %d, want 1", maxLength, gb.MaxQueueLength)
+		}
+	}
+
+	gs := newTestConfig(t, `"QueryLimit": -5`)
+	if gs.broker.MaxQueueLength != 30 {
+		t.Errorf("QueryLimit -5: broker MaxQueueLength = %d, want default 30", gs.broker.MaxQueueLength)
+	}
+}
+
+func TestGateBrokerSlowBatchDoesNotBlockQueue(t *testing.T) {
+	slow := ReqObject{Clu: "CLU_test", Id: "DOU0001", Kind: "Light", Cmd: "SET"}
+	fast := ReqObject{Clu: "CLU_test", Id: "DOU0002", Kind: "Light", Cmd: "SET"}
+
+	// GATE hangs on the first object until the request is abandoned
+	received := make(chan struct{})
+	gate := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		obj := ReqObject{}
+		json.NewDecoder(r.Body).Decode(&obj)
+		if obj.SameObject(slow) {
+			close(received)
+			<-r.Context().Done()
+			return
+		}
+		obj.Cmd = ""
+		json.NewEncoder(w).Encode(obj)
+	}))
+	t.Cleanup(gate.Close)
+
+	gb := newTestSetter(&testUpdater{}, gate.URL)
+	gb.BatchTimeout = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		<-gb.Done()
+	}()
+	go gb.Run(ctx)
+
+	slowErr := make(chan error, 1)
+	go func() {
+		_, err := gb.Do(context.Background(), slow)
+		slowErr <- err
+	}()
+	<-received
+
+	// second caller gives up much earlier than gateRequestTimeout, slow batch is abandoned before
+	reqCtx, reqCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer reqCancel()
+	data, err := gb.Do(reqCtx, fast)
+	if err != nil {
+		t.Fatalf("Do behind slow batch error = %v, want nil", err)
+	}
+	if len(data) != 1 || !data[0].SameObject(fast) {
+		t.Fatalf("Do behind slow batch data = %+v, want object received from GATE", data)
+	}
+
+	if err := <-slowErr; err == nil {
+		t.Fatal("slow batch error = nil, want timeout")
+	}
+}
@@ -0,0 +1,20 @@
+-- Sends button event to grengate input server (/event endpoint).
+-- GATE_HTTP->grengate_event is HttpRequest object configured with:
+--   Host: http://<grengate ip>:<InputServerPort>, Path: /event, Method: POST,
+--   RequestType: JSON, ResponseType: JSON
+--
+-- Usage in button events (clu, id - same as in grengate config, event - single / double / long):
+--   OnClick:       SendButtonEvent("CLU_012abcde", "DIN2890", "single")
+--   OnDoubleClick: SendButtonEvent("CLU_012abcde", "DIN2890", "double")
+--   OnHold:        SendButtonEvent("CLU_012abcde", "DIN2890", "long")
+
+function SendButtonEvent(clu, id, event)
+	local body = {}
+
+	body.Clu = clu
+	body.Id = id
+	body.Event = event
+
+	GATE_HTTP->grengate_event->SetRequestBody(body)
+	GATE_HTTP->grengate_event->SendRequest()
+end
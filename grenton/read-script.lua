@@ -20,6 +20,29 @@ function ReadLight(clu, id)
 	return Light
 end
 
+function ReadDimmer(clu, id)
+	local Dimmer = {}
+
+	-- dimmer value is 0-1, converting to percents
+	Dimmer.Brightness = math.floor(_G[clu]:execute(0, id .. ":get(0)") * 100 + 0.5)
+	Dimmer.State = Dimmer.Brightness > 0
+
+	return Dimmer
+end
+
+function ReadLed(clu, id)
+	local Led = {}
+
+	-- LED RGBW module color features, values 0-255
+	Led.Red = _G[clu]:execute(0, id .. ":get(3)")
+	Led.Green = _G[clu]:execute(0, id .. ":get(4)")
+	Led.Blue = _G[clu]:execute(0, id .. ":get(5)")
+	Led.White = _G[clu]:execute(0, id .. ":get(6)")
+	Led.State = (Led.Red + Led.Green + Led.Blue + Led.White) > 0
+
+	return Led
+end
+
 function ReadThermo(clu, thermo, sensor)
 	local Thermo = {}
 
@@ -30,6 +53,8 @@ function ReadThermo(clu, thermo, sensor)
 	Thermo.TempSetpoint = _G[clu]:execute(0, thermo .. ":get(3)")
 	Thermo.Mode = _G[clu]:execute(0, thermo .. ":get(8)")
 	Thermo.State = _G[clu]:execute(0, thermo .. ":get(6)")
+	-- ControlDirection: 0 - heating, 1 - cooling
+	Thermo.Direction = _G[clu]:execute(0, thermo .. ":get(7)")
 
 	Thermo.TempCurrent = _G[clu]:execute(0, "getVar(\"" .. sensor .. "\")")
 
@@ -41,6 +66,10 @@ function ReadShutter(clu, id)
 
 	Shutter.MaxTime = _G[clu]:execute(0, id .. ":get(3)")
 	Shutter.State = _G[clu]:execute(0, id .. ":get(2)")
+	-- lamel (slat) position, 0 - 90 degrees
+	Shutter.Lamel = _G[clu]:execute(0, id .. ":get(5)")
+	-- position reported by newer firmware, 0 - closed, 100 - open
+	Shutter.Position = _G[clu]:execute(0, id .. ":get(7)")
 
 	return Shutter
 end
@@ -57,14 +86,125 @@ function ReadMotionSensor(clu, id)
 	return MotionSensor
 end
 
+function ReadContactSensor(clu, id)
+	local ContactSensor = {}
+
+	if _G[clu]:execute(0, id .. ":get(0)") == 1 then
+		ContactSensor.State = true
+	else
+		ContactSensor.State = false
+	end
+
+	return ContactSensor
+end
+
+function ReadSensorValue(clu, id, source)
+	-- reading user variable if source provided, otherwise sensor value feature
+	if source ~= nil and source ~= "" then
+		return _G[clu]:execute(0, "getVar(\"" .. source .. "\")")
+	end
+
+	return _G[clu]:execute(0, id .. ":get(0)")
+end
+
+function ReadTemperatureSensor(clu, id, source)
+	local TemperatureSensor = {}
+
+	TemperatureSensor.Value = ReadSensorValue(clu, id, source)
+
+	return TemperatureSensor
+end
+
+function ReadHumiditySensor(clu, id, source)
+	local HumiditySensor = {}
+
+	HumiditySensor.Value = ReadSensorValue(clu, id, source)
+
+	return HumiditySensor
+end
+
+function ReadLightSensor(clu, id, source)
+	local LightSensor = {}
+
+	LightSensor.Value = ReadSensorValue(clu, id, source)
+
+	return LightSensor
+end
+
+function ReadGarageDoor(clu, id, input)
+	local GarageDoor = {}
+
+	GarageDoor.State = _G[clu]:execute(0, id .. ":get(0)") == 1
+
+	-- input is optional limit switch, active when door is closed
+	if input ~= nil and input ~= "" then
+		GarageDoor.Closed = _G[clu]:execute(0, input .. ":get(0)") == 1
+	end
+
+	return GarageDoor
+end
+
+function ReadDoorLock(clu, id, input)
+	local DoorLock = {}
+
+	DoorLock.State = _G[clu]:execute(0, id .. ":get(0)") == 1
+
+	-- input is optional door contact, active when door is closed
+	if input ~= nil and input ~= "" then
+		DoorLock.Closed = _G[clu]:execute(0, input .. ":get(0)") == 1
+	end
+
+	return DoorLock
+end
+
+function ReadFan(clu, id, auto)
+	local Fan = {}
+
+	Fan.Value = _G[clu]:execute(0, id .. ":get(0)")
+
+	-- auto is optional user variable enabling automatic mode
+	if auto ~= nil and auto ~= "" then
+		Fan.AutoMode = _G[clu]:execute(0, "getVar(\"" .. auto .. "\")") == 1
+	end
+
+	return Fan
+end
+
+function ReadValve(clu, id)
+	local Valve = {}
+
+	Valve.State = _G[clu]:execute(0, id .. ":get(0)") == 1
+
+	return Valve
+end
+
+function ReadAlarmSensor(clu, id)
+	local AlarmSensor = {}
+
+	if _G[clu]:execute(0, id .. ":get(0)") == 1 then
+		AlarmSensor.State = true
+	else
+		AlarmSensor.State = false
+	end
+
+	return AlarmSensor
+end
+
+function ReadSecuritySystem(clu, stateVar)
+	local SecuritySystem = {}
+
+	SecuritySystem.Value = _G[clu]:execute(0, "getVar(\"" .. stateVar .. "\")")
+
+	return SecuritySystem
+end
 
 function ReadSwitch(clu, id)
 	local Switch = {}
 
-	if _G[clu]:execute(0, id .. ":get(3)") == 1 then
-		Switch = true
+	if _G[clu]:execute(0, id .. ":get(0)") == 1 then
+		Switch.State = true
 	else
-		Switch = false
+		Switch.State = false
 	end
 
 	return Switch
@@ -84,6 +224,14 @@ for ix, req in ipairs(reqbody) do
 			rl.Light = ReadLight(rl.Clu, rl.Id)
 		end
 
+		if rl.Kind == "Dimmer" then
+			rl.Dimmer = ReadDimmer(rl.Clu, rl.Id)
+		end
+
+		if rl.Kind == "Led" then
+			rl.Led = ReadLed(rl.Clu, rl.Id)
+		end
+
 		if rl.Kind == "Thermo" then
 			rl.Thermo = ReadThermo(rl.Clu, rl.Id, req.Source)
 		end
@@ -96,10 +244,50 @@ for ix, req in ipairs(reqbody) do
 			rl.MotionSensor = ReadMotionSensor(rl.Clu, rl.Id)
 		end
 
+		if rl.Kind == "ContactSensor" then
+			rl.ContactSensor = ReadContactSensor(rl.Clu, rl.Id)
+		end
+
+		if rl.Kind == "TemperatureSensor" then
+			rl.TemperatureSensor = ReadTemperatureSensor(rl.Clu, rl.Id, req.Source)
+		end
+
+		if rl.Kind == "HumiditySensor" then
+			rl.HumiditySensor = ReadHumiditySensor(rl.Clu, rl.Id, req.Source)
+		end
+
+		if rl.Kind == "LightSensor" then
+			rl.LightSensor = ReadLightSensor(rl.Clu, rl.Id, req.Source)
+		end
+
+		if rl.Kind == "LeakSensor" or rl.Kind == "SmokeSensor" or rl.Kind == "CarbonMonoxideSensor" then
+			rl[rl.Kind] = ReadAlarmSensor(rl.Clu, rl.Id)
+		end
+
 		if rl.Kind == "Switch" then
 			rl.Switch = ReadSwitch(rl.Clu, rl.Id)
 		end
 
+		if rl.Kind == "GarageDoor" then
+			rl.GarageDoor = ReadGarageDoor(rl.Clu, rl.Id, req.Source)
+		end
+
+		if rl.Kind == "DoorLock" then
+			rl.DoorLock = ReadDoorLock(rl.Clu, rl.Id, req.Source)
+		end
+
+		if rl.Kind == "Fan" then
+			rl.Fan = ReadFan(rl.Clu, rl.Id, req.Source)
+		end
+
+		if rl.Kind == "Valve" then
+			rl.Valve = ReadValve(rl.Clu, rl.Id)
+		end
+
+		if rl.Kind == "SecuritySystem" then
+			rl.SecuritySystem = ReadSecuritySystem(rl.Clu, req.Source)
+		end
+
 		table.insert(resp, rl)
 	end
 
@@ -20,16 +20,41 @@ function ReadLight(clu, id)
 	return Light
 end
 
+function ReadDimmer(clu, id)
+	local Dimmer = {}
+
+	-- dimmer value is 0-1, converting to percents
+	Dimmer.Brightness = math.floor(_G[clu]:execute(0, id .. ":get(0)") * 100 + 0.5)
+	Dimmer.State = Dimmer.Brightness > 0
+
+	return Dimmer
+end
+
+function ReadLed(clu, id)
+	local Led = {}
+
+	-- LED RGBW module color features, values 0-255
+	Led.Red = _G[clu]:execute(0, id .. ":get(3)")
+	Led.Green = _G[clu]:execute(0, id .. ":get(4)")
+	Led.Blue = _G[clu]:execute(0, id .. ":get(5)")
+	Led.White = _G[clu]:execute(0, id .. ":get(6)")
+	Led.State = (Led.Red + Led.Green + Led.Blue + Led.White) > 0
+
+	return Led
+end
+
 function ReadThermo(clu, thermo, sensor)
 	local Thermo = {}
 
-	Thermo.TempMin = _G[clu]:execute(0, thermo .. ":get(8)")
-	Thermo.TempMax = _G[clu]:execute(0, thermo .. ":get(9)")
+	Thermo.TempMin = _G[clu]:execute(0, thermo .. ":get(10)")
+	Thermo.TempMax = _G[clu]:execute(0, thermo .. ":get(11)")
 	Thermo.TempTarget = _G[clu]:execute(0, thermo .. ":get(12)")
 	Thermo.TempHoliday = _G[clu]:execute(0, thermo .. ":get(4)")
 	Thermo.TempSetpoint = _G[clu]:execute(0, thermo .. ":get(3)")
 	Thermo.Mode = _G[clu]:execute(0, thermo .. ":get(8)")
 	Thermo.State = _G[clu]:execute(0, thermo .. ":get(6)")
+	-- ControlDirection: 0 - heating, 1 - cooling
+	Thermo.Direction = _G[clu]:execute(0, thermo .. ":get(7)")
 
 	Thermo.TempCurrent = _G[clu]:execute(0, "getVar(\"" .. sensor .. "\")")
 
@@ -41,10 +66,81 @@ function ReadShutter(clu, id)
 
 	Shutter.MaxTime = _G[clu]:execute(0, id .. ":get(3)")
 	Shutter.State = _G[clu]:execute(0, id .. ":get(2)")
+	-- lamel (slat) position, 0 - 90 degrees
+	Shutter.Lamel = _G[clu]:execute(0, id .. ":get(5)")
+	-- position reported by newer firmware, 0 - closed, 100 - open
+	Shutter.Position = _G[clu]:execute(0, id .. ":get(7)")
 
 	return Shutter
 end
 
+function ReadSwitch(clu, id)
+	local Switch = {}
+
+	if _G[clu]:execute(0, id .. ":get(0)") == 1 then
+		Switch.State = true
+	else
+		Switch.State = false
+	end
+
+	return Switch
+end
+
+function ReadGarageDoor(clu, id, input)
+	local GarageDoor = {}
+
+	GarageDoor.State = _G[clu]:execute(0, id .. ":get(0)") == 1
+
+	-- input is optional limit switch, active when door is closed
+	if input ~= nil and input ~= "" then
+		GarageDoor.Closed = _G[clu]:execute(0, input .. ":get(0)") == 1
+	end
+
+	return GarageDoor
+end
+
+function ReadDoorLock(clu, id, input)
+	local DoorLock = {}
+
+	DoorLock.State = _G[clu]:execute(0, id .. ":get(0)") == 1
+
+	-- input is optional door contact, active when door is closed
+	if input ~= nil and input ~= "" then
+		DoorLock.Closed = _G[clu]:execute(0, input .. ":get(0)") == 1
+	end
+
+	return DoorLock
+end
+
+function ReadFan(clu, id, auto)
+	local Fan = {}
+
+	Fan.Value = _G[clu]:execute(0, id .. ":get(0)")
+
+	-- auto is optional user variable enabling automatic mode
+	if auto ~= nil and auto ~= "" then
+		Fan.AutoMode = _G[clu]:execute(0, "getVar(\"" .. auto .. "\")") == 1
+	end
+
+	return Fan
+end
+
+function ReadValve(clu, id)
+	local Valve = {}
+
+	Valve.State = _G[clu]:execute(0, id .. ":get(0)") == 1
+
+	return Valve
+end
+
+function ReadSecuritySystem(clu, stateVar)
+	local SecuritySystem = {}
+
+	SecuritySystem.Value = _G[clu]:execute(0, "getVar(\"" .. stateVar .. "\")")
+
+	return SecuritySystem
+end
+
 function SetLight(clu, id, light)
 	-- temporary workaround for fibaro wall plug
 	if id == "TMP0001" then
@@ -63,15 +159,114 @@ function SetLight(clu, id, light)
 	end
 end
 
+function SetDimmer(clu, id, dimmer)
+	local value = dimmer.Brightness / 100
+
+	if dimmer.State ~= true then
+		value = 0
+	end
+
+	_G[clu]:execute(0, id .. ":set(0, " .. value .. ")")
+end
+
+function SetLed(clu, id, led)
+	_G[clu]:execute(0, id .. ":set(3, " .. led.Red .. ")")
+	_G[clu]:execute(0, id .. ":set(4, " .. led.Green .. ")")
+	_G[clu]:execute(0, id .. ":set(5, " .. led.Blue .. ")")
+
+	if led.Rgbw == true then
+		_G[clu]:execute(0, id .. ":set(6, " .. led.White .. ")")
+	end
+end
+
+function SetSwitch(clu, id, switch)
+	if switch.State == true then
+		_G[clu]:execute(0, id .. ":set(0, 1)")
+	else
+		_G[clu]:execute(0, id .. ":set(0, 0)")
+	end
+end
+
+function SetGarageDoor(clu, id, door)
+	if door.Pulse == true then
+		-- SwitchOn with time, relay is switched off after PulseTime ms
+		_G[clu]:execute(0, id .. ":execute(0, " .. door.PulseTime .. ")")
+		return
+	end
+
+	if door.State == true then
+		_G[clu]:execute(0, id .. ":set(0, 1)")
+	else
+		_G[clu]:execute(0, id .. ":set(0, 0)")
+	end
+end
+
+function SetDoorLock(clu, id, lock)
+	-- SwitchOn with time, output is switched off (strike secured) after UnlockTime
+	if lock.State == true then
+		_G[clu]:execute(0, id .. ":execute(0, " .. (lock.UnlockTime * 1000) .. ")")
+	end
+end
+
+function SetFan(clu, id, fan)
+	_G[clu]:execute(0, id .. ":set(0, " .. fan.Value .. ")")
+
+	if fan.Auto ~= nil and fan.Auto ~= "" then
+		if fan.AutoMode == true then
+			_G[clu]:execute(0, "setVar(\"" .. fan.Auto .. "\", 1)")
+		else
+			_G[clu]:execute(0, "setVar(\"" .. fan.Auto .. "\", 0)")
+		end
+	end
+end
+
+function SetValve(clu, id, valve)
+	if valve.State == true then
+		_G[clu]:execute(0, id .. ":set(0, 1)")
+	else
+		_G[clu]:execute(0, id .. ":set(0, 0)")
+	end
+end
+
+function SetSecuritySystem(clu, id, security)
+	-- arming script is called when configured, otherwise state variable is set directly
+	if security.Script ~= nil and security.Script ~= "" then
+		_G[clu]:execute(0, security.Script .. "()")
+	else
+		_G[clu]:execute(0, "setVar(\"" .. security.StateVar .. "\", " .. security.Value .. ")")
+	end
+end
+
+function RunScript(clu, script, params)
+	local args = {}
+
+	if params ~= nil then
+		for ix, param in ipairs(params) do
+			if type(param) == "string" then
+				args[ix] = "\"" .. param .. "\""
+			else
+				args[ix] = tostring(param)
+			end
+		end
+	end
+
+	_G[clu]:execute(0, script .. "(" .. table.concat(args, ", ") .. ")")
+end
+
 function SetThermo(clu, id, thermo)
 
 	_G[clu]:execute(0, id .. ":set(3, " .. thermo.TempSetpoint .. ")")
+	-- HolidayModeValue, temperature used in holiday (eco) mode
+	if thermo.TempHoliday ~= nil and thermo.TempHoliday > 0 then
+		_G[clu]:execute(0, id .. ":set(4, " .. thermo.TempHoliday .. ")")
+	end
 	_G[clu]:execute(0, id .. ":set(6, " .. thermo.State .. ")")
+	_G[clu]:execute(0, id .. ":set(7, " .. thermo.Direction .. ")")
 	_G[clu]:execute(0, id .. ":set(8, " .. thermo.Mode .. ")")
 
 end
 
-function SetShutter(clu, id, cmd)
+function SetShutter(clu, id, cmd, shutter)
 
 	if cmd == "MOVEUP" then
 		_G[clu]:execute(0, id .. ":execute(0, 0)")
@@ -82,6 +277,14 @@ function SetShutter(clu, id, cmd)
 	if cmd == "STOP" then
 		_G[clu]:execute(0, id .. ":execute(3, 0)")
 	end
+	if cmd == "POSITION" and shutter ~= nil then
+		-- SetPosition
+		_G[clu]:execute(0, id .. ":execute(9, " .. shutter.Position .. ")")
+	end
+	if cmd == "LAMEL" and shutter ~= nil then
+		-- SetLamelPosition
+		_G[clu]:execute(0, id .. ":execute(10, " .. shutter.Lamel .. ")")
+	end
 
 end
 
@@ -96,16 +299,60 @@ if req ~= nil and (req.Clu ~= nil) and (_G[req.Clu] ~= nil) then
 		resp.Light = ReadLight(req.Clu, req.Id)
 	end
 
+	if req.Kind == "Dimmer" then
+		SetDimmer(req.Clu, req.Id, req.Dimmer)
+		resp.Dimmer = ReadDimmer(req.Clu, req.Id)
+	end
+
+	if req.Kind == "Led" then
+		SetLed(req.Clu, req.Id, req.Led)
+		resp.Led = ReadLed(req.Clu, req.Id)
+	end
+
+	if req.Cmd == "SCRIPT" and req.Scene ~= nil then
+		RunScript(req.Clu, req.Scene.Script, req.Scene.Params)
+	end
+
 	if req.Kind == "Thermo" then
 		SetThermo(req.Clu, req.Id, req.Thermo)
-		resp.Thermo = ReadThermo(req.Clu, req.Id, req.Sensor)
+		resp.Thermo = ReadThermo(req.Clu, req.Id, req.Thermo.Source)
 	end
 
 	if req.Kind == "Shutter" then
-		SetShutter(req.Clu, req.Id, req.Cmd)
+		SetShutter(req.Clu, req.Id, req.Cmd, req.Shutter)
 		resp.Shutter = ReadShutter(req.Clu, req.Id)
 	end
 
+	if req.Kind == "Switch" then
+		SetSwitch(req.Clu, req.Id, req.Switch)
+		resp.Switch = ReadSwitch(req.Clu, req.Id)
+	end
+
+	if req.Kind == "GarageDoor" then
+		SetGarageDoor(req.Clu, req.Id, req.GarageDoor)
+		resp.GarageDoor = ReadGarageDoor(req.Clu, req.Id, req.GarageDoor.Input)
+	end
+
+	if req.Kind == "DoorLock" then
+		SetDoorLock(req.Clu, req.Id, req.DoorLock)
+		resp.DoorLock = ReadDoorLock(req.Clu, req.Id, req.DoorLock.Input)
+	end
+
+	if req.Kind == "Fan" then
+		SetFan(req.Clu, req.Id, req.Fan)
+		resp.Fan = ReadFan(req.Clu, req.Id, req.Fan.Auto)
+	end
+
+	if req.Kind == "Valve" then
+		SetValve(req.Clu, req.Id, req.Valve)
+		resp.Valve = ReadValve(req.Clu, req.Id)
+	end
+
+	if req.Kind == "SecuritySystem" then
+		SetSecuritySystem(req.Clu, req.Id, req.SecuritySystem)
+		resp.SecuritySystem = ReadSecuritySystem(req.Clu, req.SecuritySystem.StateVar)
+	end
+
 end
 
 GATE_HTTP->homebridge->SetResponseBody(resp)
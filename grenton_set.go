@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -26,12 +28,23 @@ type GrentonSet struct {
 	HkPin  string
 	HkPath string
 
+	ShutterStatePath string
+
 	FreshInSeconds  int
 	CycleInSeconds  int
 	Verbose         bool
 	PerformAutotest bool
 	QueryLimit      int
 
+	// ReadRetries, RetryDelayInMs: failed read requests are retried with exponential backoff (-1 disables retries)
+	ReadRetries    int
+	RetryDelayInMs int
+	// BreakerThreshold, BreakerProbeInSeconds: after BreakerThreshold failed requests GATE is considered unavailable,
+	// requests are stopped and GATE is probed every BreakerProbeInSeconds.
+	// Breaker is shared by reads and writes (both go to the same GATE), so failed writes count towards opening it too
+	BreakerThreshold      int
+	BreakerProbeInSeconds int
+
 	lastUpdated   time.Time
 	freshDuration time.Duration
 	cycleDuration time.Duration
@@ -39,6 +52,13 @@ type GrentonSet struct {
 
 	broker GateBroker
 	setter GateBroker
+	// readBreaker and writeBreaker are separate, failing writes (ex. to one broken object) do not stop reads
+	readBreaker  *circuitBreaker
+	writeBreaker *circuitBreaker
+	// ctx is cancelled when grengate is stopping, set by StartCycling
+	ctx context.Context
+
+	shutterStore *shutterStore
 }
 
 // Debugf logs info, when Verbose option is on
@@ -86,7 +106,16 @@ func (gs *GrentonSet) Config(path string) error {
 		gs.cycleDuration = 10 * time.Second
 	}
 
-	if gs.QueryLimit == 0 {
+	for _, clu := range gs.Clus {
+		for _, ss := range clu.SecuritySystems {
+			err = ss.initValues()
+			if err != nil {
+				return fmt.Errorf("GrentonSet Config: %w", err)
+			}
+		}
+	}
+
+	if gs.QueryLimit <= 0 {
 		gs.QueryLimit = 30
 	}
 
@@ -94,14 +123,43 @@ func (gs *GrentonSet) Config(path string) error {
 		gs.HkPath = "hk"
 	}
 
+	if gs.ShutterStatePath == "" {
+		gs.ShutterStatePath = filepath.Join(filepath.Dir(filepath.Clean(gs.HkPath)), "shutters.json")
+	}
+	gs.shutterStore = newShutterStore(gs.ShutterStatePath)
+	err = gs.shutterStore.load()
+	if err != nil {
+		gs.Logf("GrentonSet Config: loading shutter states failed, starting without: %v", err)
+	}
+
+	if gs.ReadRetries == 0 {
+		gs.ReadRetries = 3
+	}
+	if gs.RetryDelayInMs <= 0 {
+		gs.RetryDelayInMs = 500
+	}
+	if gs.BreakerThreshold <= 0 {
+		gs.BreakerThreshold = 5
+	}
+	if gs.BreakerProbeInSeconds <= 0 {
+		gs.BreakerProbeInSeconds = 30
+	}
+
+	gs.readBreaker = gs.newBreaker("read")
+	gs.writeBreaker = gs.newBreaker("write")
+
 	gs.broker = GateBroker{}
-	gs.broker.Init(gs, gs.QueryLimit, gs.freshDuration)
+	gs.broker.Init(gs, gs.QueryLimit, gs.freshDuration, gs.readBreaker)
 	gs.broker.PostPath = gs.Host + gs.ReadPath
+	gs.broker.Retries = max(gs.ReadRetries, 0)
+	gs.broker.RetryDelay = time.Duration(gs.RetryDelayInMs) * time.Millisecond
 
 	gs.setter = GateBroker{}
-	gs.setter.Init(gs, 1, 200*time.Millisecond)
+	gs.setter.Init(gs, 1, 200*time.Millisecond, gs.writeBreaker)
 	gs.setter.PostPath = gs.GetSetPath()
 
+	gs.ctx = context.Background()
+
 	return nil
 }
 
@@ -139,6 +197,16 @@ func (gs *GrentonSet) Refresh() {
 				query = append(query, light.Req)
 			}
 		}
+		for _, dimmer := range clu.Dimmers {
+			if dimmer != nil {
+				query = append(query, dimmer.Req)
+			}
+		}
+		for _, led := range clu.Leds {
+			if led != nil {
+				query = append(query, led.Req)
+			}
+		}
 		for _, thermo := range clu.Therms {
 			if thermo != nil {
 				query = append(query, thermo.Req)
@@ -154,11 +222,73 @@ func (gs *GrentonSet) Refresh() {
 				query = append(query, mosens.Req)
 			}
 		}
+		for _, cosens := range clu.ContactSensors {
+			if cosens != nil {
+				query = append(query, cosens.Req)
+			}
+		}
+		for _, tesens := range clu.TemperatureSensors {
+			if tesens != nil {
+				query = append(query, tesens.Req)
+			}
+		}
+		for _, husens := range clu.HumiditySensors {
+			if husens != nil {
+				query = append(query, husens.Req)
+			}
+		}
+		for _, lisens := range clu.LightSensors {
+			if lisens != nil {
+				query = append(query, lisens.Req)
+			}
+		}
+		for _, kind := range alarmSensorKinds {
+			for _, alsens := range clu.AlarmSensors(kind) {
+				if alsens != nil {
+					query = append(query, alsens.Req)
+				}
+			}
+		}
+		for _, sw := range clu.Switches {
+			if sw != nil {
+				query = append(query, sw.Req)
+			}
+		}
+		for _, gdo := range clu.GarageDoors {
+			if gdo != nil {
+				query = append(query, gdo.Req)
+			}
+		}
+		for _, dlo := range clu.DoorLocks {
+			if dlo != nil {
+				query = append(query, dlo.Req)
+			}
+		}
+		for _, fan := range clu.Fans {
+			if fan != nil {
+				query = append(query, fan.Req)
+			}
+		}
+		for _, vlv := range clu.Valves {
+			if vlv != nil {
+				query = append(query, vlv.Req)
+			}
+		}
+		for _, sec := range clu.SecuritySystems {
+			if sec != nil {
+				query = append(query, sec.Req)
+			}
+		}
 	}
 
-	objectsPending := gs.broker.Queue(nil, query...)
-	for len(objectsPending) > 0 {
-		objectsPending = gs.broker.Queue(nil, objectsPending...)
+	// next cycle starts new refresh, objects not sent until then are dropped
+	ctx, cancel := gs.requestContext(gs.cycleDuration)
+	defer cancel()
+
+	_, err := gs.broker.Do(ctx, query...)
+	if err != nil {
+		gs.Error(fmt.Errorf("GrentonSet Refresh failed: %w", err))
+		return
 	}
 
 	gs.lastUpdated = time.Now()
@@ -170,11 +300,11 @@ func (gs *GrentonSet) Refresh() {
 func (gs *GrentonSet) RequestAndUpdate(query []ReqObject) error {
 	gs.Logf("GrentonSet RequestAndUpdate: started [%v]", &gs)
 
-	objectsPending := gs.broker.Queue(nil, query...)
-	for len(objectsPending) > 0 {
-		objectsPending = gs.broker.Queue(nil, objectsPending...)
-	}
-	return nil
+	ctx, cancel := gs.requestContext(gateRequestTimeout)
+	defer cancel()
+
+	_, err := gs.broker.Do(ctx, query...)
+	return err
 }
 
 func (gs *GrentonSet) update(data []ReqObject) {
@@ -190,6 +320,20 @@ func (gs *GrentonSet) update(data []ReqObject) {
 				gs.Debugf("GrentonSet RequestAndUpdate: found light from request, state: %+v\n", object)
 				err = light.LoadReqObject(object)
 			}
+		case "Dimmer":
+			var dimmer *Dimmer
+			dimmer, err = gs.FindDimmer(object.Clu, object.Id)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found dimmer from request, state: %+v\n", object)
+				err = dimmer.LoadReqObject(object)
+			}
+		case "Led":
+			var led *Led
+			led, err = gs.FindLed(object.Clu, object.Id)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found led from request, state: %+v\n", object)
+				err = led.LoadReqObject(object)
+			}
 		case "Thermo":
 			var thermo *Thermo
 			thermo, err = gs.FindThermo(object.Clu, object.Id)
@@ -211,6 +355,86 @@ func (gs *GrentonSet) update(data []ReqObject) {
 				gs.Debugf("GrentonSet RequestAndUpdate: found motion sensor from request, state: %v\n", object)
 				err = sensor.LoadReqObject(object)
 			}
+		case "ContactSensor":
+			var sensor *ContactSensor
+			sensor, err = gs.FindContactSensor(object.Clu, object.Id)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found contact sensor from request, state: %v\n", object)
+				err = sensor.LoadReqObject(object)
+			}
+		case "TemperatureSensor":
+			var sensor *TemperatureSensor
+			sensor, err = gs.FindTemperatureSensor(object.Clu, object.Id)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found temperature sensor from request, state: %v\n", object)
+				err = sensor.LoadReqObject(object)
+			}
+		case "HumiditySensor":
+			var sensor *HumiditySensor
+			sensor, err = gs.FindHumiditySensor(object.Clu, object.Id)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found humidity sensor from request, state: %v\n", object)
+				err = sensor.LoadReqObject(object)
+			}
+		case "LightSensor":
+			var sensor *LightSensor
+			sensor, err = gs.FindLightSensor(object.Clu, object.Id)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found light sensor from request, state: %v\n", object)
+				err = sensor.LoadReqObject(object)
+			}
+		case "LeakSensor", "SmokeSensor", "CarbonMonoxideSensor":
+			var sensor *AlarmSensor
+			sensor, err = gs.FindAlarmSensor(object.Clu, object.Id, object.Kind)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found alarm sensor from request, state: %v\n", object)
+				err = sensor.LoadReqObject(object)
+			}
+		case "Switch":
+			var sw *Switch
+			sw, err = gs.FindSwitch(object.Clu, object.Id)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found switch from request, state: %+v\n", object)
+				err = sw.LoadReqObject(object)
+			}
+		case "GarageDoor":
+			var door *GarageDoor
+			door, err = gs.FindGarageDoor(object.Clu, object.Id)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found garage door from request, state: %+v\n", object)
+				err = door.LoadReqObject(object)
+			}
+		case "DoorLock":
+			var lock *DoorLock
+			lock, err = gs.FindDoorLock(object.Clu, object.Id)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found door lock from request, state: %+v\n", object)
+				err = lock.LoadReqObject(object)
+			}
+		case "Fan":
+			var fan *Fan
+			fan, err = gs.FindFan(object.Clu, object.Id)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found fan from request, state: %+v\n", object)
+				err = fan.LoadReqObject(object)
+			}
+		case "Valve":
+			var valve *Valve
+			valve, err = gs.FindValve(object.Clu, object.Id)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found valve from request, state: %+v\n", object)
+				err = valve.LoadReqObject(object)
+			}
+		case "Scene":
+			// scenes are stateless, nothing to load
+			gs.Debugf("GrentonSet RequestAndUpdate: scene script called: %+v\n", object)
+		case "SecuritySystem":
+			var security *SecuritySystem
+			security, err = gs.FindSecuritySystem(object.Clu, object.Id)
+			if err == nil {
+				gs.Debugf("GrentonSet RequestAndUpdate: found security system from request, state: %+v\n", object)
+				err = security.LoadReqObject(object)
+			}
 		}
 		if err != nil {
 			gs.Error(errors.Wrapf(err, "RequestAndUpdate loading [%s|%s] failed.", object.Clu, object.Id))
@@ -218,73 +442,205 @@ func (gs *GrentonSet) update(data []ReqObject) {
 	}
 }
 
-// FindThermo returns a Thermo object belonging to selected clu and with selected id
-func (gs *GrentonSet) FindThermo(fClu, fLight string) (found *Thermo, err error) {
-	gs.Debugf("GrentonSet FindThermo: Looking for thermo: in %s id: %s\n", fLight, fClu)
-	for _, clu := range gs.Clus {
-		if clu.GetMixedId() == fClu {
-			for _, thermo := range clu.Therms {
-				if thermo.GetMixedId() == fLight && thermo != nil {
-					found = thermo
-					err = nil
-					return
-				}
+// newBreaker returns configured circuit breaker logging GATE availability changes for requests of given kind
+func (gs *GrentonSet) newBreaker(kind string) *circuitBreaker {
+	return &circuitBreaker{
+		Threshold:   gs.BreakerThreshold,
+		ProbePeriod: time.Duration(gs.BreakerProbeInSeconds) * time.Second,
+		changed: func(from, to breakerState) {
+			switch to {
+			case breakerOpen:
+				gs.Logf("GrentonSet: GATE unavailable for %s, circuit breaker %s -> %s, next probe in %ds", kind, from, to, gs.BreakerProbeInSeconds)
+			case breakerHalfOpen:
+				gs.Logf("GrentonSet: probing GATE with %s, circuit breaker %s -> %s", kind, from, to)
+			default:
+				gs.Logf("GrentonSet: GATE available for %s, circuit breaker %s -> %s", kind, from, to)
 			}
-		}
+		},
 	}
-	err = fmt.Errorf("Thermostat not found [clu: %s id: %s]", fLight, fClu)
-	return
 }
 
-// FindLight returns a Light object belonging to selected clu and with selected id
-func (gs *GrentonSet) FindLight(fClu, fLight string) (found *Light, err error) {
-	gs.Debugf("GrentonSet FindLight: Looking for light: in %s id: %s\n", fLight, fClu)
-	for _, clu := range gs.Clus {
-		if clu.GetMixedId() == fClu {
-			for _, light := range clu.Lights {
-				if light.GetMixedId() == fLight && light != nil {
-					found = light
-					err = nil
-					return
-				}
-			}
+// fault sets StatusFault on objects which were not read or set because of GATE request failure,
+// objects without StatusFault reject HomeKit writes until next successful read
+func (gs *GrentonSet) fault(data []ReqObject, err error) {
+	type faultReporter interface {
+		SetFault(bool)
+	}
+
+	for _, object := range data {
+		var reporter faultReporter
+		var findErr error
+
+		switch object.Kind {
+		case "ContactSensor":
+			reporter, findErr = gs.FindContactSensor(object.Clu, object.Id)
+		case "MotionSensor":
+			reporter, findErr = gs.FindMotionSensor(object.Clu, object.Id)
+		case "TemperatureSensor":
+			reporter, findErr = gs.FindTemperatureSensor(object.Clu, object.Id)
+		case "HumiditySensor":
+			reporter, findErr = gs.FindHumiditySensor(object.Clu, object.Id)
+		case "LightSensor":
+			reporter, findErr = gs.FindLightSensor(object.Clu, object.Id)
+		case "LeakSensor", "SmokeSensor", "CarbonMonoxideSensor":
+			reporter, findErr = gs.FindAlarmSensor(object.Clu, object.Id, object.Kind)
+		case "Light":
+			reporter, findErr = gs.FindLight(object.Clu, object.Id)
+		case "Dimmer":
+			reporter, findErr = gs.FindDimmer(object.Clu, object.Id)
+		case "Led":
+			reporter, findErr = gs.FindLed(object.Clu, object.Id)
+		case "Thermo":
+			reporter, findErr = gs.FindThermo(object.Clu, object.Id)
+		case "Shutter":
+			reporter, findErr = gs.FindShutter(object.Clu, object.Id)
+		case "Switch":
+			reporter, findErr = gs.FindSwitch(object.Clu, object.Id)
+		case "GarageDoor":
+			reporter, findErr = gs.FindGarageDoor(object.Clu, object.Id)
+		case "DoorLock":
+			reporter, findErr = gs.FindDoorLock(object.Clu, object.Id)
+		case "Fan":
+			reporter, findErr = gs.FindFan(object.Clu, object.Id)
+		case "Valve":
+			reporter, findErr = gs.FindValve(object.Clu, object.Id)
+		case "SecuritySystem":
+			reporter, findErr = gs.FindSecuritySystem(object.Clu, object.Id)
+		default:
+			// scenes are not read, fault would never be cleared
+			continue
+		}
+
+		if findErr == nil {
+			gs.Debugf("GrentonSet fault: setting fault on [%s|%s]: %v\n", object.Clu, object.Id, err)
+			reporter.SetFault(true)
 		}
 	}
-	err = fmt.Errorf("Light not found [clu: %s id: %s]", fLight, fClu)
-	return
 }
 
-// FindShutter returns a Shutter object belnging to selected clu
-func (gs *GrentonSet) FindShutter(fClu, fShutter string) (found *Shutter, err error) {
-	gs.Debugf("GrentonSet FindShutter: Looking for shutter: in %s id: %s\n", fShutter, fClu)
+// findObject returns object with provided id from selected clu, ids are compared case-insensitively
+func findObject[E any, T interface {
+	*E
+	GetMixedId() string
+}](gs *GrentonSet, name, fClu, fId string, objects func(*Clu) []T) (T, error) {
+	gs.Debugf("GrentonSet find: Looking for %s in clu %s with id %s\n", name, fClu, fId)
 	for _, clu := range gs.Clus {
-		if clu.GetMixedId() == fClu {
-			for _, sht := range clu.Shutters {
-				if sht.GetMixedId() == fShutter && sht != nil {
-					found = sht
-					err = nil
-					return
-				}
+		if !strings.EqualFold(clu.GetMixedId(), fClu) {
+			continue
+		}
+		for _, obj := range objects(clu) {
+			if obj != nil && strings.EqualFold(obj.GetMixedId(), fId) {
+				return obj, nil
 			}
 		}
 	}
-	err = fmt.Errorf("Shutter not found [clu: %s id: %s]", fShutter, fClu)
-	return
+	return nil, fmt.Errorf("%s not found [clu: %s id: %s]", name, fClu, fId)
+}
+
+// FindThermo returns a Thermo object from selected clu and with provided id
+func (gs *GrentonSet) FindThermo(fClu, fId string) (*Thermo, error) {
+	return findObject(gs, "thermostat", fClu, fId, func(clu *Clu) []*Thermo { return clu.Therms })
+}
+
+// FindLight returns a Light object from selected clu and with provided id
+func (gs *GrentonSet) FindLight(fClu, fId string) (*Light, error) {
+	return findObject(gs, "light", fClu, fId, func(clu *Clu) []*Light { return clu.Lights })
+}
+
+// FindDimmer returns a Dimmer object from selected clu and with provided id
+func (gs *GrentonSet) FindDimmer(fClu, fId string) (*Dimmer, error) {
+	return findObject(gs, "dimmer", fClu, fId, func(clu *Clu) []*Dimmer { return clu.Dimmers })
+}
+
+// FindLed returns a Led object from selected clu and with provided id
+func (gs *GrentonSet) FindLed(fClu, fId string) (*Led, error) {
+	return findObject(gs, "led", fClu, fId, func(clu *Clu) []*Led { return clu.Leds })
+}
+
+// FindShutter returns a Shutter object from selected clu and with provided id
+func (gs *GrentonSet) FindShutter(fClu, fId string) (*Shutter, error) {
+	return findObject(gs, "shutter", fClu, fId, func(clu *Clu) []*Shutter { return clu.Shutters })
 }
 
 // FindMotionSensor returns a MotionSensor object from selected clu and with provided id
-func (gs *GrentonSet) FindMotionSensor(fClu, fSensor string) (*MotionSensor, error) {
-	gs.Debugf("GrentonSet FindMotionSensor: Looking for sensor in clu %s with id %s\n", fClu, fSensor)
-	for _, clu := range gs.Clus {
-		if strings.EqualFold(clu.GetMixedId(), fClu) {
-			for _, sens := range clu.MotionSensors {
-				if strings.EqualFold(sens.GetMixedId(), fSensor) && sens != nil {
-					return sens, nil
-				}
-			}
+func (gs *GrentonSet) FindMotionSensor(fClu, fId string) (*MotionSensor, error) {
+	return findObject(gs, "motion sensor", fClu, fId, func(clu *Clu) []*MotionSensor { return clu.MotionSensors })
+}
+
+// FindContactSensor returns a ContactSensor object from selected clu and with provided id
+func (gs *GrentonSet) FindContactSensor(fClu, fId string) (*ContactSensor, error) {
+	return findObject(gs, "contact sensor", fClu, fId, func(clu *Clu) []*ContactSensor { return clu.ContactSensors })
+}
+
+// FindTemperatureSensor returns a TemperatureSensor object from selected clu and with provided id
+func (gs *GrentonSet) FindTemperatureSensor(fClu, fId string) (*TemperatureSensor, error) {
+	return findObject(gs, "temperature sensor", fClu, fId, func(clu *Clu) []*TemperatureSensor { return clu.TemperatureSensors })
+}
+
+// FindHumiditySensor returns a HumiditySensor object from selected clu and with provided id
+func (gs *GrentonSet) FindHumiditySensor(fClu, fId string) (*HumiditySensor, error) {
+	return findObject(gs, "humidity sensor", fClu, fId, func(clu *Clu) []*HumiditySensor { return clu.HumiditySensors })
+}
+
+// FindLightSensor returns a LightSensor object from selected clu and with provided id
+func (gs *GrentonSet) FindLightSensor(fClu, fId string) (*LightSensor, error) {
+	return findObject(gs, "light sensor", fClu, fId, func(clu *Clu) []*LightSensor { return clu.LightSensors })
+}
+
+// FindButton returns a Button object from selected clu and with provided id
+func (gs *GrentonSet) FindButton(fClu, fId string) (*Button, error) {
+	return findObject(gs, "button", fClu, fId, func(clu *Clu) []*Button { return clu.Buttons })
+}
+
+// FindAlarmSensor returns an AlarmSensor object of selected kind from selected clu and with provided id
+func (gs *GrentonSet) FindAlarmSensor(fClu, fId, kind string) (*AlarmSensor, error) {
+	return findObject(gs, kind, fClu, fId, func(clu *Clu) []*AlarmSensor { return clu.AlarmSensors(kind) })
+}
+
+// FindInput returns any object which can be updated by InputServer push (motion, contact or alarm sensor)
+func (gs *GrentonSet) FindInput(fClu, fId string) (GrentonInput, error) {
+	if sensor, err := gs.FindMotionSensor(fClu, fId); err == nil {
+		return sensor, nil
+	}
+	if sensor, err := gs.FindContactSensor(fClu, fId); err == nil {
+		return sensor, nil
+	}
+	for _, kind := range alarmSensorKinds {
+		if sensor, err := gs.FindAlarmSensor(fClu, fId, kind); err == nil {
+			return sensor, nil
 		}
 	}
-	return nil, fmt.Errorf("sensor not found [clu: %s id: %s]", fClu, fSensor)
+	return nil, fmt.Errorf("input not found [clu: %s id: %s]", fClu, fId)
+}
+
+// FindSwitch returns a Switch object from selected clu and with provided id
+func (gs *GrentonSet) FindSwitch(fClu, fId string) (*Switch, error) {
+	return findObject(gs, "switch", fClu, fId, func(clu *Clu) []*Switch { return clu.Switches })
+}
+
+// FindGarageDoor returns a GarageDoor object from selected clu and with provided id
+func (gs *GrentonSet) FindGarageDoor(fClu, fId string) (*GarageDoor, error) {
+	return findObject(gs, "garage door", fClu, fId, func(clu *Clu) []*GarageDoor { return clu.GarageDoors })
+}
+
+// FindDoorLock returns a DoorLock object from selected clu and with provided id
+func (gs *GrentonSet) FindDoorLock(fClu, fId string) (*DoorLock, error) {
+	return findObject(gs, "door lock", fClu, fId, func(clu *Clu) []*DoorLock { return clu.DoorLocks })
+}
+
+// FindFan returns a Fan object from selected clu and with provided id
+func (gs *GrentonSet) FindFan(fClu, fId string) (*Fan, error) {
+	return findObject(gs, "fan", fClu, fId, func(clu *Clu) []*Fan { return clu.Fans })
+}
+
+// FindValve returns a Valve object from selected clu and with provided id
+func (gs *GrentonSet) FindValve(fClu, fId string) (*Valve, error) {
+	return findObject(gs, "valve", fClu, fId, func(clu *Clu) []*Valve { return clu.Valves })
+}
+
+// FindSecuritySystem returns a SecuritySystem object from selected clu and with provided id
+func (gs *GrentonSet) FindSecuritySystem(fClu, fId string) (*SecuritySystem, error) {
+	return findObject(gs, "security system", fClu, fId, func(clu *Clu) []*SecuritySystem { return clu.SecuritySystems })
 }
 
 // CheckFreshness checks if time passed from last refresh is greater than set treshold
@@ -321,16 +677,50 @@ func (gs *GrentonSet) TestAllGrentonGate() {
 	gs.Logf("GrentonSet TestAllGrentonGate: GATE test finished")
 }
 
-// StartCycling starts a goroutine which periodically refreshes state of all objects
-func (gs *GrentonSet) StartCycling() {
+// requestContext returns context for GATE request limited by timeout, it is not cancelled on shutdown,
+// so requests already queued are sent while brokers drain
+func (gs *GrentonSet) requestContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(gs.ctx), timeout)
+}
+
+// StartCycling starts GATE brokers and a goroutine which periodically refreshes state of all objects,
+// both are stopped when ctx is done
+func (gs *GrentonSet) StartCycling(ctx context.Context) {
+	gs.ctx = ctx
+
+	// brokers are stopped after shutters, so last shutter commands are sent
+	brokerCtx, stopBrokers := context.WithCancel(context.WithoutCancel(ctx))
+	go gs.broker.Run(brokerCtx)
+	go gs.setter.Run(brokerCtx)
+
 	go func() {
 		gs.cycling = time.NewTicker(gs.cycleDuration)
+		defer gs.cycling.Stop()
 
 		for {
 			select {
 			case <-gs.cycling.C:
 				go gs.Refresh()
+			case <-ctx.Done():
+				gs.closeShutters()
+				stopBrokers()
+				return
 			}
 		}
 	}()
 }
+
+// closeShutters stops shutters moved by grengate
+func (gs *GrentonSet) closeShutters() {
+	for _, clu := range gs.Clus {
+		for _, sht := range clu.Shutters {
+			sht.Close()
+		}
+	}
+}
+
+// Wait blocks until brokers are stopped and queued requests are sent
+func (gs *GrentonSet) Wait() {
+	<-gs.broker.Done()
+	<-gs.setter.Done()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+	"github.com/brutella/hap/service"
+)
+
+// heaterCoolerStates are names of Thermo target states, index is HomeKit heater cooler target state value
+var heaterCoolerStates = []string{"auto", "heat", "cool"}
+
+// appendHeaterCooler exposes Thermo as HomeKit heater cooler (ex. for split AC units driven by Grenton thermostat)
+func (gt *Thermo) appendHeaterCooler() *accessory.A {
+	info := accessory.Info{
+		Name:         gt.Name,
+		SerialNumber: fmt.Sprintf("%d", gt.Id),
+		Manufacturer: "Grenton",
+		Model:        gt.Kind,
+	}
+
+	gt.hkAccessory = accessory.New(info, accessory.TypeAirConditioner)
+	gt.hkAccessory.Id = gt.GetLongId()
+
+	gt.hkHeaterCooler = service.NewHeaterCooler()
+	gt.hkAccessory.AddS(gt.hkHeaterCooler.S)
+
+	// only mapped target states can be selected
+	validStates := []int{}
+	for hkState, name := range heaterCoolerStates {
+		if _, ok := gt.Modes[name]; ok {
+			validStates = append(validStates, hkState)
+		}
+	}
+	gt.hkHeaterCooler.TargetHeaterCoolerState.ValidVals = validStates
+
+	gt.hkHeating = characteristic.NewHeatingThresholdTemperature()
+	gt.hkHeaterCooler.AddC(gt.hkHeating.C)
+	gt.hkCooling = characteristic.NewCoolingThresholdTemperature()
+	gt.hkHeaterCooler.AddC(gt.hkCooling.C)
+
+	gt.appendHoliday()
+	gt.appendSchedule()
+
+	gt.hkHeaterCooler.Active.OnValueRemoteUpdate(gt.SetActive)
+	gt.hkHeaterCooler.TargetHeaterCoolerState.OnValueRemoteUpdate(gt.SetHeaterCoolerState)
+	gt.hkHeating.OnValueRemoteUpdate(gt.SetHeatingThreshold)
+	gt.hkCooling.OnValueRemoteUpdate(gt.SetCoolingThreshold)
+	gt.guardWrites(gt.hkHeaterCooler.Active.C, gt.hkHeaterCooler.TargetHeaterCoolerState.C, gt.hkHeating.C, gt.hkCooling.C)
+
+	gt.clu.set.Logf("HK HeaterCooler added (id: %x)", gt.hkAccessory.Id)
+	return gt.hkAccessory
+}
+
+// syncHeaterCooler sets HK heater cooler values based on Thermo values
+func (gt *Thermo) syncHeaterCooler() {
+	if gt.TempMax > gt.TempMin {
+		for _, threshold := range []*characteristic.Float{gt.hkHeating.Float, gt.hkCooling.Float} {
+			threshold.SetMinValue(gt.TempMin)
+			threshold.SetMaxValue(gt.TempMax)
+		}
+	}
+
+	gt.hkHeaterCooler.CurrentTemperature.SetValue(gt.TempCurrent)
+
+	// inactive heater cooler shows target state it will be started in
+	target := gt.targetState
+	if target == characteristic.TargetHeatingCoolingStateOff {
+		gt.hkHeaterCooler.Active.SetValue(characteristic.ActiveInactive)
+		gt.hkHeaterCooler.CurrentHeaterCoolerState.SetValue(characteristic.CurrentHeaterCoolerStateInactive)
+		target = gt.lastTarget
+	} else {
+		gt.hkHeaterCooler.Active.SetValue(characteristic.ActiveActive)
+		if gt.Direction == 0 {
+			gt.hkHeaterCooler.CurrentHeaterCoolerState.SetValue(characteristic.CurrentHeaterCoolerStateHeating)
+		} else {
+			gt.hkHeaterCooler.CurrentHeaterCoolerState.SetValue(characteristic.CurrentHeaterCoolerStateCooling)
+		}
+	}
+	if ix := slices.Index(heaterCoolerStates, thermoStates[target]); ix >= 0 {
+		gt.hkHeaterCooler.TargetHeaterCoolerState.SetValue(ix)
+	}
+
+	// threshold of direction not used yet is shown as current setpoint
+	gt.hkHeating.SetValue(gt.TempSetpoint)
+	if gt.heatingThreshold > 0 {
+		gt.hkHeating.SetValue(gt.heatingThreshold)
+	}
+	gt.hkCooling.SetValue(gt.TempSetpoint)
+	if gt.coolingThreshold > 0 {
+		gt.hkCooling.SetValue(gt.coolingThreshold)
+	}
+}
+
+// SetActive switches thermostat off, or on in last used target state
+func (gt *Thermo) SetActive(active int) {
+	gt.block.Lock()
+	state := characteristic.TargetHeatingCoolingStateOff
+	if active == characteristic.ActiveActive {
+		state = gt.lastTarget
+	}
+
+	err := gt.applyTarget(state)
+	gt.block.Unlock()
+	if err != nil {
+		gt.clu.set.Error(fmt.Errorf("Thermo SetActive: %w", err))
+		return
+	}
+	gt.hkHeaterCooler.Active.SetValue(active)
+
+	gt.send("SetActive")
+}
+
+// SetHeaterCoolerState switches between auto, heating and cooling
+func (gt *Thermo) SetHeaterCoolerState(hcState int) {
+	if hcState < 0 || hcState >= len(heaterCoolerStates) {
+		gt.clu.set.Error(fmt.Errorf("Thermo SetHeaterCoolerState: invalid target state (%d)", hcState))
+		return
+	}
+
+	gt.block.Lock()
+	err := gt.applyTarget(slices.Index(thermoStates, heaterCoolerStates[hcState]))
+	gt.block.Unlock()
+	if err != nil {
+		gt.clu.set.Error(fmt.Errorf("Thermo SetHeaterCoolerState: %w", err))
+		return
+	}
+	gt.hkHeaterCooler.TargetHeaterCoolerState.SetValue(hcState)
+
+	gt.send("SetHeaterCoolerState")
+}
+
+// SetHeatingThreshold sets heating temperature, it is sent as setpoint when thermostat is heating
+func (gt *Thermo) SetHeatingThreshold(temp float64) {
+	gt.hkHeating.SetValue(temp)
+	gt.block.Lock()
+	gt.heatingThreshold = temp
+	heating := gt.Direction == 0
+	if heating {
+		gt.TempSetpoint = temp
+	}
+	gt.block.Unlock()
+
+	if heating {
+		gt.send("SetHeatingThreshold")
+	}
+}
+
+// SetCoolingThreshold sets cooling temperature, it is sent as setpoint when thermostat is cooling
+func (gt *Thermo) SetCoolingThreshold(temp float64) {
+	gt.hkCooling.SetValue(temp)
+	gt.block.Lock()
+	gt.coolingThreshold = temp
+	cooling := gt.Direction != 0
+	if cooling {
+		gt.TempSetpoint = temp
+	}
+	gt.block.Unlock()
+
+	if cooling {
+		gt.send("SetCoolingThreshold")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/service"
+)
+
+// HumiditySensor reads relative humidity (in percents) from Grenton sensor feature or from user variable (when Source is set)
+type HumiditySensor struct {
+	SensorObject
+
+	Source string `json:",omitempty"`
+
+	Value float64
+
+	hkAccessory *accessory.A
+	hkService   *service.HumiditySensor
+}
+
+func (hs *HumiditySensor) Init(clu *Clu) *accessory.A {
+	hs.clu = clu
+
+	hs.Req = ReqObject{
+		Kind:   "HumiditySensor",
+		Clu:    hs.clu.Id,
+		Id:     hs.GetMixedId(),
+		Source: hs.Source,
+	}
+	return hs.appendHk()
+}
+
+func (hs *HumiditySensor) GetA() *accessory.A {
+	return hs.hkAccessory
+}
+
+func (hs *HumiditySensor) appendHk() *accessory.A {
+	info := accessory.Info{
+		Name:         hs.Name,
+		SerialNumber: fmt.Sprintf("%d", hs.Id),
+		Manufacturer: "Grenton",
+		Model:        hs.Kind,
+	}
+
+	hs.hkAccessory = accessory.New(info, accessory.TypeSensor)
+	hs.hkAccessory.Id = hs.GetLongId()
+
+	hs.hkService = service.NewHumiditySensor()
+	hs.addFault(hs.hkService.S)
+	hs.hkAccessory.AddS(hs.hkService.S)
+
+	hs.clu.set.Logf("HK HumiditySensor added (id: %x)", hs.hkAccessory.Id)
+
+	return hs.hkAccessory
+}
+
+func (hs *HumiditySensor) Sync() {
+	value := hs.Value
+	if value < 0 {
+		value = 0
+	}
+	if value > 100 {
+		value = 100
+	}
+	hs.hkService.CurrentRelativeHumidity.SetValue(value)
+}
+
+// LoadReqObject checks object received from http request end reads it into HumiditySensor
+func (hs *HumiditySensor) LoadReqObject(obj ReqObject) error {
+	if err := hs.checkLoaded(obj, "HumiditySensor", obj.HumiditySensor == nil); err != nil {
+		return err
+	}
+
+	hs.Value = obj.HumiditySensor.Value
+	hs.Sync()
+
+	return nil
+}
@@ -42,7 +42,7 @@ func (is *InputServer) HandleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sensor, err := is.gSet.FindMotionSensor(inputPayload.Clu, inputPayload.Id)
+	sensor, err := is.gSet.FindInput(inputPayload.Clu, inputPayload.Id)
 	if err != nil {
 		is.gSet.Error(err)
 		return
@@ -53,6 +53,94 @@ func (is *InputServer) HandleRequest(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// HandleEvent triggers button events (single, double, long) pushed by CLU
+func (is *InputServer) HandleEvent(w http.ResponseWriter, r *http.Request) {
+	is.gSet.Debugf("input server handling event from host: %s\n", r.Host)
+
+	if !strings.EqualFold(r.Header.Get("Content-Type"), "application/json") {
+		http.Error(w, "unsupported Media Type, expected application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	type event struct {
+		Clu   string
+		Id    string
+		Event string
+	}
+
+	eventPayload := &event{}
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(eventPayload)
+	if err != nil {
+		is.gSet.Error(errors.Wrapf(err, "failed to decode event body from host: %s", r.Host))
+		http.Error(w, "failed to decode request body", http.StatusBadRequest)
+		return
+	}
+
+	button, err := is.gSet.FindButton(eventPayload.Clu, eventPayload.Id)
+	if err != nil {
+		is.gSet.Error(err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	err = button.Trigger(eventPayload.Event)
+	if err != nil {
+		is.gSet.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleCalibrate starts shutter calibration (in background), travel times are logged and saved
+func (is *InputServer) HandleCalibrate(w http.ResponseWriter, r *http.Request) {
+	is.gSet.Debugf("input server handling calibration request from host: %s\n", r.Host)
+
+	if !strings.EqualFold(r.Header.Get("Content-Type"), "application/json") {
+		http.Error(w, "unsupported Media Type, expected application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	type calibration struct {
+		Clu string
+		Id  string
+	}
+
+	calibrationPayload := &calibration{}
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(calibrationPayload)
+	if err != nil {
+		is.gSet.Error(errors.Wrapf(err, "failed to decode calibration body from host: %s", r.Host))
+		http.Error(w, "failed to decode request body", http.StatusBadRequest)
+		return
+	}
+
+	shutter, err := is.gSet.FindShutter(calibrationPayload.Clu, calibrationPayload.Id)
+	if err != nil {
+		is.gSet.Error(err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if shutter.Calibrating() {
+		http.Error(w, errCalibrationInProgress.Error(), http.StatusConflict)
+		return
+	}
+
+	go func() {
+		err := shutter.Calibrate()
+		if err != nil {
+			is.gSet.Error(err)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
 func (is *InputServer) Run() error {
 	return is.server.ListenAndServe()
 }
@@ -64,6 +152,8 @@ func NewInputServer(grentonSet *GrentonSet, port int) *InputServer {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/update", is.HandleRequest)
+	mux.HandleFunc("/event", is.HandleEvent)
+	mux.HandleFunc("/calibrate", is.HandleCalibrate)
 
 	is.server = http.Server{
 		Addr:           fmt.Sprintf(":%d", port),
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+	"github.com/brutella/hap/service"
+)
+
+// IrrigationSystem groups all valves of the Clu in one HomeKit accessory
+type IrrigationSystem struct {
+	CluObject
+
+	valves []*Valve
+
+	hkAccessory *accessory.A
+	hkService   *service.IrrigationSystem
+	hkFault     *characteristic.StatusFault
+}
+
+func (is *IrrigationSystem) Init(clu *Clu, valves []*Valve) *accessory.A {
+	is.clu = clu
+	is.valves = valves
+
+	return is.appendHk()
+}
+
+func (is *IrrigationSystem) GetA() *accessory.A {
+	return is.hkAccessory
+}
+
+func (is *IrrigationSystem) appendHk() *accessory.A {
+	info := accessory.Info{
+		Name:         is.Name,
+		SerialNumber: fmt.Sprintf("%d", is.Id),
+		Manufacturer: "Grenton",
+		Model:        is.Kind,
+	}
+
+	is.hkAccessory = accessory.New(info, accessory.TypeSprinkler)
+	is.hkAccessory.Id = is.GetLongId()
+
+	is.hkService = service.NewIrrigationSystem()
+	is.hkService.Primary = true
+	is.hkService.Active.SetValue(characteristic.ActiveActive)
+	is.hkService.ProgramMode.SetValue(characteristic.ProgramModeNoProgramScheduled)
+	is.hkService.Active.OnValueRemoteUpdate(is.SetActive)
+	is.hkFault = characteristic.NewStatusFault()
+	is.hkFault.SetValue(characteristic.StatusFaultNoFault)
+	is.hkService.AddC(is.hkFault.C)
+	is.hkAccessory.AddS(is.hkService.S)
+
+	label := service.NewServiceLabel()
+	label.ServiceLabelNamespace.SetValue(characteristic.ServiceLabelNamespaceArabicNumerals)
+	is.hkAccessory.AddS(label.S)
+
+	for ix, valve := range is.valves {
+		valve.system = is
+
+		index := characteristic.NewServiceLabelIndex()
+		index.SetValue(ix + 1)
+		valve.hkService.AddC(index.C)
+
+		configured := characteristic.NewIsConfigured()
+		configured.SetValue(characteristic.IsConfiguredConfigured)
+		valve.hkService.AddC(configured.C)
+
+		name := characteristic.NewName()
+		name.SetValue(valve.Name)
+		valve.hkService.AddC(name.C)
+
+		is.hkAccessory.AddS(valve.hkService.S)
+		is.hkService.AddS(valve.hkService.S)
+	}
+
+	is.clu.set.Logf("HK IrrigationSystem added (id: %x, zones: %d)", is.hkAccessory.Id, len(is.valves))
+	return is.hkAccessory
+}
+
+// Sync sets InUse when any of the valves is in use and StatusFault when any of them is unavailable
+func (is *IrrigationSystem) Sync() {
+	inUse := characteristic.InUseNotInUse
+	fault := characteristic.StatusFaultNoFault
+	for _, valve := range is.valves {
+		if valve.IsOpen() {
+			inUse = characteristic.InUseInUse
+		}
+		if valve.unavailable.Load() {
+			fault = characteristic.StatusFaultGeneralFault
+		}
+	}
+	is.hkService.InUse.SetValue(inUse)
+	is.hkFault.SetValue(fault)
+}
+
+// SetActive closes all valves when irrigation system is deactivated
+func (is *IrrigationSystem) SetActive(active int) {
+	if active == characteristic.ActiveActive {
+		return
+	}
+
+	for _, valve := range is.valves {
+		if valve.IsOpen() {
+			valve.SetActive(characteristic.ActiveInactive)
+		}
+	}
+}
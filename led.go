@@ -0,0 +1,354 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+)
+
+// Led represents Grenton LED RGB/RGBW controller, color values are in 0-255 range
+type Led struct {
+	CluObject
+
+	// Rgbw is a config option, set when controller has white channel
+	Rgbw bool `json:",omitempty"`
+	// ColorTemp is a config option, adds HomeKit ColorTemperature characteristic
+	ColorTemp bool `json:",omitempty"`
+
+	State bool
+	Red,
+	Green,
+	Blue,
+	White int
+
+	hue            float64
+	saturation     float64
+	brightness     int
+	lastBrightness int
+
+	hk          *accessory.ColoredLightbulb
+	hkColorTemp *characteristic.ColorTemperature
+
+	sendTimer *time.Timer
+	pending   bool
+	block     sync.Mutex
+}
+
+func (gl *Led) LoadReqObject(obj ReqObject) error {
+	if obj.Kind != "Led" {
+		return fmt.Errorf("Led LoadReqObject: wrong object kind (%s)", obj.Kind)
+	}
+
+	if obj.Led == nil {
+		return fmt.Errorf("Led LoadReqObject: missing Led object")
+	}
+
+	gl.SetFault(false)
+
+	gl.block.Lock()
+	defer gl.block.Unlock()
+
+	// values set in HomeKit and not sent yet are newer
+	if gl.pending {
+		return nil
+	}
+
+	gl.Red = clampByte(obj.Led.Red)
+	gl.Green = clampByte(obj.Led.Green)
+	gl.Blue = clampByte(obj.Led.Blue)
+	gl.White = clampByte(obj.Led.White)
+
+	r, g, b := gl.Red, gl.Green, gl.Blue
+	if gl.Rgbw {
+		r, g, b = clampByte(r+gl.White), clampByte(g+gl.White), clampByte(b+gl.White)
+	}
+
+	hue, saturation, value := rgbToHsv(r, g, b)
+	gl.State = value > 0
+	if gl.State {
+		// hue of grey color is undefined, keeping previous one
+		if saturation > 0 {
+			gl.hue = hue
+		}
+		gl.saturation = saturation
+		gl.brightness = int(math.Round(value))
+		gl.lastBrightness = gl.brightness
+	}
+	gl.Sync()
+
+	return nil
+}
+
+func (gl *Led) InitAll() {
+	gl.Req = ReqObject{
+		Kind: "Led",
+		Clu:  gl.clu.Id,
+		Id:   gl.GetMixedId(),
+	}
+	gl.lastBrightness = 100
+	gl.AppendHk()
+}
+
+func (gl *Led) AppendHk() *accessory.ColoredLightbulb {
+	info := accessory.Info{
+		Name:         gl.Name,
+		SerialNumber: fmt.Sprintf("%d", gl.Id),
+		Manufacturer: "Grenton",
+		Model:        gl.Kind,
+	}
+
+	gl.hk = accessory.NewColoredLightbulb(info)
+	gl.hk.Id = gl.GetLongId()
+
+	gl.hk.Lightbulb.On.OnValueRemoteUpdate(gl.SetOn)
+	gl.hk.Lightbulb.Brightness.OnValueRemoteUpdate(gl.SetBrightness)
+	gl.hk.Lightbulb.Hue.OnValueRemoteUpdate(gl.SetHue)
+	gl.hk.Lightbulb.Saturation.OnValueRemoteUpdate(gl.SetSaturation)
+	gl.guardWrites(gl.hk.Lightbulb.On.C, gl.hk.Lightbulb.Brightness.C, gl.hk.Lightbulb.Hue.C, gl.hk.Lightbulb.Saturation.C)
+
+	if gl.ColorTemp {
+		gl.hkColorTemp = characteristic.NewColorTemperature()
+		gl.hkColorTemp.OnValueRemoteUpdate(gl.SetColorTemperature)
+		gl.guardWrites(gl.hkColorTemp.C)
+		gl.hk.Lightbulb.AddC(gl.hkColorTemp.C)
+	}
+
+	gl.clu.set.Logf("HK ColoredLightbulb added (id: %x, type: %d", gl.hk.A.Id, gl.hk.A.Type)
+	return gl.hk
+}
+
+func (gl *Led) Sync() {
+	gl.hk.Lightbulb.On.SetValue(gl.State)
+	if gl.State {
+		gl.hk.Lightbulb.Brightness.SetValue(gl.brightness)
+		gl.hk.Lightbulb.Hue.SetValue(gl.hue)
+		gl.hk.Lightbulb.Saturation.SetValue(gl.saturation)
+	}
+}
+
+// SetOn switches led on (restoring last known brightness) or off
+func (gl *Led) SetOn(state bool) {
+	gl.block.Lock()
+	defer gl.block.Unlock()
+
+	if state == gl.State {
+		return
+	}
+
+	gl.State = state
+	if state {
+		gl.brightness = gl.lastBrightness
+	}
+
+	gl.send()
+}
+
+// SetBrightness sets led brightness in percents, 0 turns led off
+func (gl *Led) SetBrightness(brightness int) {
+	gl.block.Lock()
+	defer gl.block.Unlock()
+
+	gl.brightness = clampPercent(brightness)
+	gl.State = gl.brightness > 0
+	if gl.State {
+		gl.lastBrightness = gl.brightness
+	}
+
+	gl.send()
+}
+
+// SetHue sets led hue in degrees (0-360)
+func (gl *Led) SetHue(hue float64) {
+	gl.block.Lock()
+	defer gl.block.Unlock()
+
+	gl.hue = hue
+	gl.send()
+}
+
+// SetSaturation sets led saturation in percents
+func (gl *Led) SetSaturation(saturation float64) {
+	gl.block.Lock()
+	defer gl.block.Unlock()
+
+	gl.saturation = saturation
+	gl.send()
+}
+
+// SetColorTemperature converts color temperature (in mireds) to hue and saturation
+func (gl *Led) SetColorTemperature(mired int) {
+	if mired <= 0 {
+		return
+	}
+
+	gl.block.Lock()
+	defer gl.block.Unlock()
+
+	r, g, b := kelvinToRgb(1000000 / float64(mired))
+	gl.hue, gl.saturation, _ = rgbToHsv(r, g, b)
+
+	gl.hk.Lightbulb.Hue.SetValue(gl.hue)
+	gl.hk.Lightbulb.Saturation.SetValue(gl.saturation)
+
+	gl.send()
+}
+
+// updateRgbw calculates Red, Green, Blue and White values from HomeKit hue, saturation and brightness
+func (gl *Led) updateRgbw() {
+	brightness := 0
+	if gl.State {
+		brightness = gl.brightness
+	}
+
+	gl.Red, gl.Green, gl.Blue = hsvToRgb(gl.hue, gl.saturation, float64(brightness))
+	gl.White = 0
+
+	if gl.Rgbw {
+		gl.White = min(gl.Red, gl.Green, gl.Blue)
+		gl.Red -= gl.White
+		gl.Green -= gl.White
+		gl.Blue -= gl.White
+	}
+}
+
+// send schedules request to GATE, HomeKit sends every characteristic of color change separately,
+// so request is delayed by writeDebounce and covers all of them (must be called with block locked)
+func (gl *Led) send() {
+	gl.pending = true
+	if gl.sendTimer == nil {
+		gl.sendTimer = time.AfterFunc(writeDebounce, gl.flush)
+		return
+	}
+	gl.sendTimer.Reset(writeDebounce)
+}
+
+// flush sends current color to GATE
+func (gl *Led) flush() {
+	gl.block.Lock()
+	gl.updateRgbw()
+	gl.pending = false
+
+	req := gl.Req
+	// request is marshalled by GateBroker goroutine, so it gets a copy of values
+	req.Led = &Led{
+		CluObject: CluObject{Id: gl.Id, Name: gl.Name, Kind: gl.Kind},
+		Rgbw:      gl.Rgbw,
+		State:     gl.State,
+		Red:       gl.Red,
+		Green:     gl.Green,
+		Blue:      gl.Blue,
+		White:     gl.White,
+	}
+	gl.block.Unlock()
+
+	_, err := gl.SendReq(req)
+
+	if err != nil {
+		gl.clu.set.Error(fmt.Errorf("Led send: %w", err))
+	}
+}
+
+// hsvToRgb converts hue (0-360), saturation (0-100) and value (0-100) to rgb (0-255)
+func hsvToRgb(hue, saturation, value float64) (r, g, b int) {
+	h := math.Mod(hue, 360) / 60
+	if h < 0 {
+		h += 6
+	}
+	s := math.Max(0, math.Min(saturation, 100)) / 100
+	v := math.Max(0, math.Min(value, 100)) / 100
+
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h, 2)-1))
+	m := v - c
+
+	var rf, gf, bf float64
+	switch int(h) {
+	case 0:
+		rf, gf, bf = c, x, 0
+	case 1:
+		rf, gf, bf = x, c, 0
+	case 2:
+		rf, gf, bf = 0, c, x
+	case 3:
+		rf, gf, bf = 0, x, c
+	case 4:
+		rf, gf, bf = x, 0, c
+	default:
+		rf, gf, bf = c, 0, x
+	}
+
+	r = int(math.Round((rf + m) * 255))
+	g = int(math.Round((gf + m) * 255))
+	b = int(math.Round((bf + m) * 255))
+	return
+}
+
+// rgbToHsv converts rgb (0-255) to hue (0-360), saturation (0-100) and value (0-100)
+func rgbToHsv(r, g, b int) (hue, saturation, value float64) {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+
+	maxC := math.Max(rf, math.Max(gf, bf))
+	minC := math.Min(rf, math.Min(gf, bf))
+	delta := maxC - minC
+
+	value = maxC * 100
+	if maxC == 0 || delta == 0 {
+		return
+	}
+	saturation = delta / maxC * 100
+
+	switch maxC {
+	case rf:
+		hue = 60 * math.Mod((gf-bf)/delta, 6)
+	case gf:
+		hue = 60 * ((bf-rf)/delta + 2)
+	default:
+		hue = 60 * ((rf-gf)/delta + 4)
+	}
+	if hue < 0 {
+		hue += 360
+	}
+	return
+}
+
+// kelvinToRgb approximates color of black body radiation in given temperature
+func kelvinToRgb(kelvin float64) (r, g, b int) {
+	t := kelvin / 100
+
+	var rf, gf, bf float64
+	if t <= 66 {
+		rf = 255
+		gf = 99.4708025861*math.Log(t) - 161.1195681661
+	} else {
+		rf = 329.698727446 * math.Pow(t-60, -0.1332047592)
+		gf = 288.1221695283 * math.Pow(t-60, -0.0755148492)
+	}
+
+	switch {
+	case t >= 66:
+		bf = 255
+	case t <= 19:
+		bf = 0
+	default:
+		bf = 138.5177312231*math.Log(t-10) - 305.0447927307
+	}
+
+	r = clampByte(int(math.Round(rf)))
+	g = clampByte(int(math.Round(gf)))
+	b = clampByte(int(math.Round(bf)))
+	return
+}
+
+func clampByte(value int) int {
+	if value < 0 {
+		return 0
+	}
+	if value > 255 {
+		return 255
+	}
+	return value
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHsvToRgb(t *testing.T) {
+	tests := []struct {
+		name                   string
+		hue, saturation, value float64
+		r, g, b                int
+	}{
+		{"red", 0, 100, 100, 255, 0, 0},
+		{"hue 360 is red", 360, 100, 100, 255, 0, 0},
+		{"yellow", 60, 100, 100, 255, 255, 0},
+		{"green", 120, 100, 100, 0, 255, 0},
+		{"cyan", 180, 100, 100, 0, 255, 255},
+		{"blue", 240, 100, 100, 0, 0, 255},
+		{"magenta", 300, 100, 100, 255, 0, 255},
+		{"saturation 0 is white", 200, 0, 100, 255, 255, 255},
+		{"saturation 0 is gray", 90, 0, 50, 128, 128, 128},
+		{"value 0 is black", 120, 100, 0, 0, 0, 0},
+		{"negative hue", -120, 100, 100, 0, 0, 255},
+		{"out of range clamped", 0, 150, 200, 255, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, g, b := hsvToRgb(tt.hue, tt.saturation, tt.value)
+			if r != tt.r || g != tt.g || b != tt.b {
+				t.Errorf("hsvToRgb(%v, %v, %v) = (%d, %d, %d), want (%d, %d, %d)", tt.hue, tt.saturation, tt.value, r, g, b, tt.r, tt.g, tt.b)
+			}
+		})
+	}
+}
+
+func TestRgbToHsv(t *testing.T) {
+	tests := []struct {
+		name                   string
+		r, g, b                int
+		hue, saturation, value float64
+	}{
+		{"red", 255, 0, 0, 0, 100, 100},
+		{"green", 0, 255, 0, 120, 100, 100},
+		{"blue", 0, 0, 255, 240, 100, 100},
+		{"magenta", 255, 0, 255, 300, 100, 100},
+		{"white", 255, 255, 255, 0, 0, 100},
+		{"black", 0, 0, 0, 0, 0, 0},
+		{"red with blue tint", 255, 0, 51, 348, 100, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hue, saturation, value := rgbToHsv(tt.r, tt.g, tt.b)
+			if math.Abs(hue-tt.hue) > 0.01 || math.Abs(saturation-tt.saturation) > 0.01 || math.Abs(value-tt.value) > 0.01 {
+				t.Errorf("rgbToHsv(%d, %d, %d) = (%v, %v, %v), want (%v, %v, %v)", tt.r, tt.g, tt.b, hue, saturation, value, tt.hue, tt.saturation, tt.value)
+			}
+		})
+	}
+}
+
+func TestHsvRgbRoundTrip(t *testing.T) {
+	for hue := 0.0; hue < 360; hue += 15 {
+		r, g, b := hsvToRgb(hue, 100, 100)
+		gotHue, gotSaturation, gotValue := rgbToHsv(r, g, b)
+		if math.Abs(gotHue-hue) > 1 || gotSaturation != 100 || gotValue != 100 {
+			t.Errorf("hue %v: rgb (%d, %d, %d) converted back to (%v, %v, %v)", hue, r, g, b, gotHue, gotSaturation, gotValue)
+		}
+	}
+}
+
+func TestKelvinToRgb(t *testing.T) {
+	tests := []struct {
+		name    string
+		kelvin  float64
+		r, g, b int
+	}{
+		{"candle, no blue", 1000, 255, 68, 0},
+		{"daylight is white", 6600, 255, 255, 255},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, g, b := kelvinToRgb(tt.kelvin)
+			if r != tt.r || g != tt.g || b != tt.b {
+				t.Errorf("kelvinToRgb(%v) = (%d, %d, %d), want (%d, %d, %d)", tt.kelvin, r, g, b, tt.r, tt.g, tt.b)
+			}
+		})
+	}
+
+	// cold light has more blue than red, all components stay in byte range
+	r, g, b := kelvinToRgb(40000)
+	if b != 255 || r >= b || r < 0 || g < 0 || g > 255 {
+		t.Errorf("kelvinToRgb(40000) = (%d, %d, %d), want blue dominant color in byte range", r, g, b)
+	}
+}
+
+func TestClampByte(t *testing.T) {
+	tests := []struct {
+		value, want int
+	}{
+		{-1, 0},
+		{0, 0},
+		{128, 128},
+		{255, 255},
+		{256, 255},
+	}
+
+	for _, tt := range tests {
+		if got := clampByte(tt.value); got != tt.want {
+			t.Errorf("clampByte(%d) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/brutella/hap/accessory"
 )
@@ -11,6 +12,8 @@ type Light struct {
 
 	State bool
 
+	block sync.Mutex
+
 	hk *accessory.Lightbulb
 }
 
@@ -23,8 +26,12 @@ func (gl *Light) LoadReqObject(obj ReqObject) error {
 		return fmt.Errorf("Light LoadReqObject: missing Light object")
 	}
 
+	gl.SetFault(false)
+
+	gl.block.Lock()
 	gl.State = obj.Light.State
 	gl.Sync()
+	gl.block.Unlock()
 
 	return nil
 }
@@ -51,6 +58,7 @@ func (gl *Light) AppendHk() *accessory.Lightbulb {
 	gl.hk.Id = gl.GetLongId()
 
 	gl.hk.Lightbulb.On.OnValueRemoteUpdate(gl.Set)
+	gl.guardWrites(gl.hk.Lightbulb.On.C)
 	// gl.hk.Lightbulb.On.OnValueRemoteGet(gl.Get)
 
 	gl.clu.set.Logf("HK Lightbulb added (id: %x, type: %d", gl.hk.A.Id, gl.hk.A.Type)
@@ -77,11 +85,13 @@ func (gl *Light) Get() bool {
 }
 
 func (gl *Light) Set(state bool) {
-
+	gl.block.Lock()
 	gl.State = state
-
+	// copy of sent values, request is marshalled later by GateBroker goroutine
 	req := gl.Req
-	req.Light = gl
+	req.Light = &Light{CluObject: CluObject{Id: gl.Id, Name: gl.Name, Kind: gl.Kind}, State: gl.State}
+	gl.block.Unlock()
+
 	_, err := gl.SendReq(req)
 
 	if err != nil {
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/service"
+)
+
+const (
+	minAmbientLightLevel = 0.0001
+	maxAmbientLightLevel = 100000
+)
+
+// LightSensor reads illuminance (in lux) from Grenton multisensor feature or from user variable (when Source is set)
+type LightSensor struct {
+	SensorObject
+
+	Source string `json:",omitempty"`
+
+	Value float64
+
+	hkAccessory *accessory.A
+	hkService   *service.LightSensor
+}
+
+func (ls *LightSensor) Init(clu *Clu) *accessory.A {
+	ls.clu = clu
+
+	ls.Req = ReqObject{
+		Kind:   "LightSensor",
+		Clu:    ls.clu.Id,
+		Id:     ls.GetMixedId(),
+		Source: ls.Source,
+	}
+	return ls.appendHk()
+}
+
+func (ls *LightSensor) GetA() *accessory.A {
+	return ls.hkAccessory
+}
+
+func (ls *LightSensor) appendHk() *accessory.A {
+	info := accessory.Info{
+		Name:         ls.Name,
+		SerialNumber: fmt.Sprintf("%d", ls.Id),
+		Manufacturer: "Grenton",
+		Model:        ls.Kind,
+	}
+
+	ls.hkAccessory = accessory.New(info, accessory.TypeSensor)
+	ls.hkAccessory.Id = ls.GetLongId()
+
+	ls.hkService = service.NewLightSensor()
+	ls.addFault(ls.hkService.S)
+	ls.hkAccessory.AddS(ls.hkService.S)
+
+	ls.clu.set.Logf("HK LightSensor added (id: %x)", ls.hkAccessory.Id)
+
+	return ls.hkAccessory
+}
+
+// GetHkValue returns illuminance clamped to range accepted by HomeKit
+func (ls *LightSensor) GetHkValue() float64 {
+	if ls.Value < minAmbientLightLevel {
+		return minAmbientLightLevel
+	}
+	if ls.Value > maxAmbientLightLevel {
+		return maxAmbientLightLevel
+	}
+	return ls.Value
+}
+
+func (ls *LightSensor) Sync() {
+	ls.hkService.CurrentAmbientLightLevel.SetValue(ls.GetHkValue())
+}
+
+// LoadReqObject checks object received from http request end reads it into LightSensor
+func (ls *LightSensor) LoadReqObject(obj ReqObject) error {
+	if err := ls.checkLoaded(obj, "LightSensor", obj.LightSensor == nil); err != nil {
+		return err
+	}
+
+	ls.Value = obj.LightSensor.Value
+	ls.Sync()
+
+	return nil
+}
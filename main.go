@@ -25,8 +25,6 @@ var (
 func main() {
 	log.Print("Starting grengate")
 
-	ctx := context.Background()
-
 	configPath := flag.String("config", "./config.json", "config file path")
 	performAutotest := flag.Bool("do-autotest", false, "perform an autotest on startup")
 	showVersion := flag.Bool("version", false, "Show version information and exit")
@@ -53,8 +51,21 @@ func main() {
 		gren.TestAllGrentonGate()
 	}
 
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, syscall.SIGTERM)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-c
+		// Stop delivering signals.
+		signal.Stop(c)
+		// Cancel the context to stop the server and GATE brokers.
+		cancel()
+	}()
+
 	log.Println("Starting update cycles")
-	gren.StartCycling()
+	gren.StartCycling(ctx)
 
 	if gren.InputServerPort > 0 {
 		log.Printf("Starting input server (listening on port %d)\n", gren.InputServerPort)
@@ -92,23 +103,12 @@ func main() {
 
 	server.Pin = gren.HkPin
 
-	c := make(chan os.Signal)
-	signal.Notify(c, os.Interrupt)
-	signal.Notify(c, syscall.SIGTERM)
-
-	ctx, cancel := context.WithCancel(ctx)
-	go func() {
-		<-c
-		// Stop delivering signals.
-		signal.Stop(c)
-		// Cancel the context to stop the server.
-		cancel()
-	}()
-
 	err = server.ListenAndServe(ctx)
 	if err != nil {
 		log.Fatal(err)
-	} else {
-		log.Println("grengate exiting, bye.")
 	}
+
+	// requests already queued are sent before exit
+	gren.Wait()
+	log.Println("grengate exiting, bye.")
 }
@@ -2,30 +2,40 @@ package main
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/brutella/hap/accessory"
-	"github.com/brutella/hap/characteristic"
 	"github.com/brutella/hap/service"
 )
 
-const clearMotionEventDuration = 15 * time.Second
+const defaultMotionClearTime = 15
 
 type MotionSensor struct {
-	CluObject
+	SensorObject
+
+	// ClearTime is a config option, seconds after last motion when detection is cleared, new motion extends it
+	ClearTime int `json:",omitempty"`
 
 	State bool
 
-	clearTimer      *time.Timer
-	resetClearTimer chan bool
+	detected   atomic.Bool
+	lastMotion time.Time
+	clearTimer *time.Timer
+	block      sync.Mutex
+
+	occupancies []*OccupancySensor
 
 	hkAccessory *accessory.A
 	hkService   *service.MotionSensor
-	hkFault     *characteristic.StatusFault
 }
 
 func (ms *MotionSensor) Init(clu *Clu) *accessory.A {
 	ms.clu = clu
+	if ms.ClearTime <= 0 {
+		ms.ClearTime = defaultMotionClearTime
+	}
 
 	ms.Req = ReqObject{
 		Kind: "MotionSensor",
@@ -50,10 +60,7 @@ func (ms *MotionSensor) appendHk() *accessory.A {
 	ms.hkAccessory.Id = ms.GetLongId()
 
 	ms.hkService = service.NewMotionSensor()
-	ms.hkFault = characteristic.NewStatusFault()
-	ms.hkFault.SetValue(characteristic.StatusFaultNoFault)
-
-	ms.hkService.AddC(ms.hkFault.C)
+	ms.addFault(ms.hkService.S)
 	ms.hkAccessory.AddS(ms.hkService.S)
 	ms.hkService.MotionDetected.SetValue(false)
 
@@ -62,39 +69,60 @@ func (ms *MotionSensor) appendHk() *accessory.A {
 	return ms.hkAccessory
 }
 
+// Set updates input state, it is also called by InputServer.
+// Motion is detected immediately, it is cleared by timer ClearTime after the last motion.
 func (ms *MotionSensor) Set(state bool) {
+	ms.block.Lock()
 	ms.State = state
-	ms.hkService.MotionDetected.SetValue(state)
+	if !state {
+		ms.block.Unlock()
+		return
+	}
+
+	ms.lastMotion = time.Now()
+	clearTime := time.Duration(ms.ClearTime) * time.Second
+	if ms.clearTimer == nil {
+		ms.clearTimer = time.AfterFunc(clearTime, ms.clear)
+	} else {
+		ms.clearTimer.Reset(clearTime)
+	}
+	ms.block.Unlock()
+
+	ms.setDetected(true)
 }
 
-func (ms *MotionSensor) SetOn() {
-	ms.Set(true)
-	ms.clearTimer = time.NewTimer(clearMotionEventDuration)
-	go func() {
-		for {
-			select {
-			case <-ms.clearTimer.C:
-				ms.Set(false)
-				return
-			case <-ms.resetClearTimer:
-				ms.clearTimer.Stop()
-				return
-			}
-		}
-	}()
+// clear is called by timer, detection is kept when motion was retriggered meanwhile
+func (ms *MotionSensor) clear() {
+	ms.block.Lock()
+	retriggered := time.Since(ms.lastMotion) < time.Duration(ms.ClearTime)*time.Second
+	ms.block.Unlock()
+
+	if !retriggered {
+		ms.setDetected(false)
+	}
 }
 
-// LoadReqObject checks object received from http request end reads it into MotionSensor
-func (ms *MotionSensor) LoadReqObject(obj ReqObject) error {
-	if obj.Kind != "MotionSensor" {
-		return fmt.Errorf("MotionSensor LoadReqObject: wrong object kind (%s)", obj.Kind)
+func (ms *MotionSensor) setDetected(detected bool) {
+	if ms.detected.Swap(detected) == detected {
+		return
 	}
 
-	if obj.MotionSensor == nil {
-		return fmt.Errorf("MotionSensor LoadReqObject: missing MotionSensor object")
+	ms.hkService.MotionDetected.SetValue(detected)
+	for _, occupancy := range ms.occupancies {
+		occupancy.update()
 	}
+}
 
-	ms.clu.set.Debugf("MotionSensor LoadReqObject loading: \n%+v", obj)
+// Detected returns true when motion was detected within ClearTime
+func (ms *MotionSensor) Detected() bool {
+	return ms.detected.Load()
+}
+
+// LoadReqObject checks object received from http request end reads it into MotionSensor
+func (ms *MotionSensor) LoadReqObject(obj ReqObject) error {
+	if err := ms.checkLoaded(obj, "MotionSensor", obj.MotionSensor == nil); err != nil {
+		return err
+	}
 
 	ms.Set(obj.MotionSensor.State)
 
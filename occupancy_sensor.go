@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+	"github.com/brutella/hap/service"
+)
+
+// OccupancySensor aggregates motion sensors of a room, occupancy is detected while any of them detects motion
+type OccupancySensor struct {
+	CluObject
+
+	// Motion is a config option, mixed ids (ex. DIN0012) of room MotionSensors
+	Motion []string
+
+	sensors []*MotionSensor
+
+	hkAccessory *accessory.A
+	hkService   *service.OccupancySensor
+}
+
+func (ocs *OccupancySensor) Init(clu *Clu) *accessory.A {
+	ocs.clu = clu
+
+	for _, id := range ocs.Motion {
+		found := false
+		for _, ms := range clu.MotionSensors {
+			if strings.EqualFold(ms.GetMixedId(), id) {
+				ms.occupancies = append(ms.occupancies, ocs)
+				ocs.sensors = append(ocs.sensors, ms)
+				found = true
+			}
+		}
+		if !found {
+			clu.set.Logf("OccupancySensor %s: motion sensor %s not found", ocs.Name, id)
+		}
+	}
+
+	return ocs.appendHk()
+}
+
+func (ocs *OccupancySensor) GetA() *accessory.A {
+	return ocs.hkAccessory
+}
+
+func (ocs *OccupancySensor) appendHk() *accessory.A {
+	info := accessory.Info{
+		Name:         ocs.Name,
+		SerialNumber: fmt.Sprintf("%d", ocs.Id),
+		Manufacturer: "Grenton",
+		Model:        ocs.Kind,
+	}
+
+	ocs.hkAccessory = accessory.New(info, accessory.TypeSensor)
+	ocs.hkAccessory.Id = ocs.GetLongId()
+
+	ocs.hkService = service.NewOccupancySensor()
+	ocs.hkAccessory.AddS(ocs.hkService.S)
+	ocs.update()
+
+	ocs.clu.set.Logf("HK OccupancySensor added (id: %x)", ocs.hkAccessory.Id)
+
+	return ocs.hkAccessory
+}
+
+// update sets occupancy state, it is called by motion sensors when detection changes
+func (ocs *OccupancySensor) update() {
+	for _, ms := range ocs.sensors {
+		if ms.Detected() {
+			ocs.hkService.OccupancyDetected.SetValue(characteristic.OccupancyDetectedOccupancyDetected)
+			return
+		}
+	}
+	ocs.hkService.OccupancyDetected.SetValue(characteristic.OccupancyDetectedOccupancyNotDetected)
+}
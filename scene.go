@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/brutella/hap/accessory"
+)
+
+const sceneResetDelay = time.Second
+
+// Scene calls CLU script with optional parameters, presented in HomeKit as momentary switch
+type Scene struct {
+	CluObject
+
+	Script string
+	Params []interface{} `json:",omitempty"`
+
+	hk *accessory.Switch
+}
+
+func (sc *Scene) InitAll() {
+	sc.Req = ReqObject{
+		Kind: "Scene",
+		Clu:  sc.clu.Id,
+		Id:   sc.GetMixedId(),
+		Cmd:  "SCRIPT",
+	}
+	sc.AppendHk()
+}
+
+func (sc *Scene) AppendHk() *accessory.Switch {
+	info := accessory.Info{
+		Name:         sc.Name,
+		SerialNumber: fmt.Sprintf("%d", sc.Id),
+		Manufacturer: "Grenton",
+		Model:        sc.Kind,
+	}
+
+	sc.hk = accessory.NewSwitch(info)
+	sc.hk.Id = sc.GetLongId()
+
+	sc.hk.Switch.On.OnValueRemoteUpdate(sc.Set)
+
+	sc.clu.set.Logf("HK Switch (scene) added (id: %x, script: %s)", sc.hk.A.Id, sc.Script)
+	return sc.hk
+}
+
+// Set runs the scene script, switch is reset after sceneResetDelay
+func (sc *Scene) Set(state bool) {
+	if !state {
+		return
+	}
+
+	time.AfterFunc(sceneResetDelay, func() {
+		sc.hk.Switch.On.SetValue(false)
+	})
+
+	// copy of sent values, request is marshalled later by GateBroker goroutine (Script and Params are config values, not changed)
+	req := sc.Req
+	req.Scene = &Scene{
+		CluObject: CluObject{Id: sc.Id, Name: sc.Name, Kind: sc.Kind},
+		Script:    sc.Script,
+		Params:    sc.Params,
+	}
+	_, err := sc.SendReq(req)
+
+	if err != nil {
+		sc.clu.set.Error(fmt.Errorf("Scene Set: %w", err))
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+)
+
+// securityStates are names of HomeKit security system states, index is HomeKit characteristic value
+var securityStates = []string{"stay", "away", "night", "disarmed", "triggered"}
+
+// SecuritySystem is an alarm zone implemented with Grenton user variable (state) and optional scripts (arming)
+type SecuritySystem struct {
+	CluObject
+
+	// StateVar is a config option, name of user variable holding alarm state
+	StateVar string `json:",omitempty"`
+	// Values is a config option, maps state names (stay, away, night, disarmed, triggered) to StateVar values,
+	// HomeKit values (0-4) are used by default
+	Values map[string]int `json:",omitempty"`
+	// Scripts is a config option, maps target state names to CLU scripts called instead of setting StateVar
+	Scripts map[string]string `json:",omitempty"`
+
+	Value  int
+	Script string `json:",omitempty"`
+
+	currentState int
+	targetState  int
+	block        sync.Mutex
+
+	hk      *accessory.SecuritySystem
+	hkFault *characteristic.StatusFault
+}
+
+func (ss *SecuritySystem) InitAll() {
+	ss.Req = ReqObject{
+		Kind:   "SecuritySystem",
+		Clu:    ss.clu.Id,
+		Id:     ss.GetMixedId(),
+		Source: ss.StateVar,
+	}
+
+	ss.currentState = characteristic.SecuritySystemCurrentStateDisarmed
+	ss.targetState = characteristic.SecuritySystemTargetStateDisarm
+
+	ss.AppendHk()
+}
+
+// initValues fills state values missing in config with HomeKit values, every state needs a distinct value
+// as current state is read back from StateVar
+func (ss *SecuritySystem) initValues() error {
+	if ss.Values == nil {
+		ss.Values = map[string]int{}
+	}
+	for hkState, name := range securityStates {
+		if _, ok := ss.Values[name]; !ok {
+			ss.Values[name] = hkState
+		}
+	}
+
+	states := map[int]string{}
+	for _, name := range securityStates {
+		value := ss.Values[name]
+		if other, ok := states[value]; ok {
+			return fmt.Errorf("SecuritySystem %s: states %s and %s have the same value (%d)", ss.Name, other, name, value)
+		}
+		states[value] = name
+	}
+	return nil
+}
+
+func (ss *SecuritySystem) AppendHk() *accessory.SecuritySystem {
+	info := accessory.Info{
+		Name:         ss.Name,
+		SerialNumber: fmt.Sprintf("%d", ss.Id),
+		Manufacturer: "Grenton",
+		Model:        ss.Kind,
+	}
+
+	ss.hk = accessory.NewSecuritySystem(info)
+	ss.hk.Id = ss.GetLongId()
+
+	ss.hkFault = characteristic.NewStatusFault()
+	ss.hkFault.SetValue(characteristic.StatusFaultNoFault)
+	ss.hk.SecuritySystem.AddC(ss.hkFault.C)
+
+	ss.Sync()
+	ss.hk.SecuritySystem.SecuritySystemTargetState.OnValueRemoteUpdate(ss.SetTarget)
+	ss.guardWrites(ss.hk.SecuritySystem.SecuritySystemTargetState.C)
+
+	ss.clu.set.Logf("HK SecuritySystem added (id: %x)", ss.hk.A.Id)
+	return ss.hk
+}
+
+// Sync sets HK accessory values based on SecuritySystem values
+func (ss *SecuritySystem) Sync() {
+	ss.hk.SecuritySystem.SecuritySystemCurrentState.SetValue(ss.currentState)
+	ss.hk.SecuritySystem.SecuritySystemTargetState.SetValue(ss.targetState)
+}
+
+// SetFault sets HomeKit StatusFault characteristic, HomeKit writes are rejected during fault
+func (ss *SecuritySystem) SetFault(fault bool) {
+	ss.CluObject.SetFault(fault)
+	if fault {
+		ss.hkFault.SetValue(characteristic.StatusFaultGeneralFault)
+	} else {
+		ss.hkFault.SetValue(characteristic.StatusFaultNoFault)
+	}
+}
+
+// hkState returns HomeKit state matching Grenton value, false if value is not mapped
+func (ss *SecuritySystem) hkState(value int) (int, bool) {
+	for hkState, name := range securityStates {
+		if ss.Values[name] == value {
+			return hkState, true
+		}
+	}
+	return 0, false
+}
+
+// SetTarget arms or disarms the zone, by calling configured script or by setting StateVar
+func (ss *SecuritySystem) SetTarget(target int) {
+	if target < 0 || target > characteristic.SecuritySystemTargetStateDisarm {
+		ss.clu.set.Error(fmt.Errorf("SecuritySystem SetTarget: invalid target state (%d)", target))
+		return
+	}
+
+	name := securityStates[target]
+	ss.block.Lock()
+	ss.targetState = target
+	ss.Value = ss.Values[name]
+	ss.Script = ss.Scripts[name]
+	// copy of sent values, request is marshalled later by GateBroker goroutine
+	req := ss.Req
+	req.SecuritySystem = &SecuritySystem{
+		CluObject: CluObject{Id: ss.Id, Name: ss.Name, Kind: ss.Kind},
+		StateVar:  ss.StateVar,
+		Value:     ss.Value,
+		Script:    ss.Script,
+	}
+	ss.block.Unlock()
+
+	_, err := ss.SendReq(req)
+
+	if err != nil {
+		ss.clu.set.Error(fmt.Errorf("SecuritySystem SetTarget: %w", err))
+	}
+}
+
+// LoadReqObject checks object received from http request end reads it into SecuritySystem
+func (ss *SecuritySystem) LoadReqObject(obj ReqObject) error {
+	if obj.Kind != "SecuritySystem" {
+		return fmt.Errorf("SecuritySystem LoadReqObject: wrong object kind (%s)", obj.Kind)
+	}
+
+	if obj.SecuritySystem == nil {
+		return fmt.Errorf("SecuritySystem LoadReqObject: missing SecuritySystem object")
+	}
+
+	ss.clu.set.Debugf("SecuritySystem LoadReqObject loading: \n%+v", obj)
+
+	ss.SetFault(false)
+
+	state, ok := ss.hkState(obj.SecuritySystem.Value)
+	if !ok {
+		return fmt.Errorf("SecuritySystem LoadReqObject: value %d is not mapped to any state", obj.SecuritySystem.Value)
+	}
+
+	ss.block.Lock()
+	defer ss.block.Unlock()
+
+	ss.currentState = state
+	// triggered alarm keeps armed target state
+	if state != characteristic.SecuritySystemCurrentStateAlarmTriggered {
+		ss.targetState = state
+	}
+	ss.Sync()
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestSecuritySystemInitValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    map[string]int
+		duplicate bool
+	}{
+		{"defaults", nil, false},
+		{"all configured", map[string]int{"stay": 10, "away": 11, "night": 12, "disarmed": 0, "triggered": 99}, false},
+		{"configured duplicate", map[string]int{"stay": 1, "away": 1}, true},
+		{"configured value equal to default of other state", map[string]int{"disarmed": 0}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := &SecuritySystem{Values: tt.values}
+			err := ss.initValues()
+			if (err != nil) != tt.duplicate {
+				t.Fatalf("initValues() error = %v, want duplicate: %v", err, tt.duplicate)
+			}
+			if err != nil {
+				return
+			}
+
+			for hkState, name := range securityStates {
+				if got, ok := ss.hkState(ss.Values[name]); !ok || got != hkState {
+					t.Errorf("value of %s read back as state (%d, %v), want %d", name, got, ok, hkState)
+				}
+			}
+		})
+	}
+}
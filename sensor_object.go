@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/brutella/hap/characteristic"
+	"github.com/brutella/hap/service"
+)
+
+// SensorObject is embedded by read-only Grenton inputs, failed GATE read is shown with HomeKit StatusFault characteristic
+type SensorObject struct {
+	CluObject
+
+	hkFault *characteristic.StatusFault
+}
+
+// addFault adds StatusFault characteristic to sensor service
+func (so *SensorObject) addFault(s *service.S) {
+	so.hkFault = characteristic.NewStatusFault()
+	so.hkFault.SetValue(characteristic.StatusFaultNoFault)
+	s.AddC(so.hkFault.C)
+}
+
+// SetFault sets HomeKit StatusFault characteristic
+func (so *SensorObject) SetFault(fault bool) {
+	so.CluObject.SetFault(fault)
+	if fault {
+		so.hkFault.SetValue(characteristic.StatusFaultGeneralFault)
+	} else {
+		so.hkFault.SetValue(characteristic.StatusFaultNoFault)
+	}
+}
+
+// checkLoaded checks kind of object received from GATE, fault is set when sensor data is missing in it
+func (so *SensorObject) checkLoaded(obj ReqObject, kind string, missing bool) error {
+	if obj.Kind != kind {
+		return fmt.Errorf("%s LoadReqObject: wrong object kind (%s)", kind, obj.Kind)
+	}
+
+	if missing {
+		so.SetFault(true)
+		return fmt.Errorf("%s LoadReqObject: missing %s object", kind, kind)
+	}
+
+	so.clu.set.Debugf("%s LoadReqObject loading: \n%+v", kind, obj)
+	so.SetFault(false)
+
+	return nil
+}
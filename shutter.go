@@ -1,7 +1,11 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/brutella/hap/accessory"
@@ -15,8 +19,20 @@ const (
 	shutterUp shutterCmd = iota
 	shutterDown
 	shutterStop
+	shutterLamel
+	shutterPosition
+	shutterRead
 )
 
+const (
+	calibrationPollPeriod = 250 * time.Millisecond
+	calibrationTimeout    = 3 * time.Minute
+	// calibrationMaxTimeMargin covers poll period and request time, move this close to MaxTime was stopped by CLU timer
+	calibrationMaxTimeMargin = time.Second
+)
+
+var errCalibrationInProgress = errors.New("shutter calibration in progress")
+
 type ShutterAccessory struct {
 	*accessory.A
 
@@ -27,15 +43,57 @@ type Shutter struct {
 
 	hk ShutterAccessory
 
-	currentPosition int
-	targetPosition  int
-	cancelMovement  chan bool
-	moveTicker      *time.Ticker
-	looping         bool
+	// motion estimates position of shutters without feedback, it is used when estimated is set
+	motion       *shutterMotion
+	motionStatus motionStatus
+	block        sync.Mutex
+	// estimated is set for NoFeedback shutters, or when GATE response has no position
+	estimated atomic.Bool
+
+	hkCurrentTilt *characteristic.CurrentHorizontalTiltAngle
+	hkTargetTilt  *characteristic.TargetHorizontalTiltAngle
+
+	// Tilt is a config option, enables slat tilt (lamel) control
+	Tilt bool `json:",omitempty"`
+	// NoFeedback is a config option, for shutters not reporting position (estimated with timer)
+	NoFeedback bool `json:",omitempty"`
+	// UpTime, DownTime are config options, travel times in ms overriding calibrated times and MaxTime from CLU
+	UpTime   int `json:",omitempty"`
+	DownTime int `json:",omitempty"`
+	// Overrun is a config option, extra time in ms added when moving to end stop (fully open or closed)
+	Overrun int `json:",omitempty"`
+
+	calibratedUpTime   int
+	calibratedDownTime int
+	// calibrating is set during Calibrate, HomeKit commands are rejected then
+	calibrating atomic.Bool
 
 	// State: 0 - stopped; 1 - going up; 2 - going down
 	State   int
 	MaxTime int
+	// Lamel: slat position in Grenton format, 0 - 90 degrees
+	Lamel int
+	// Position: reported by Grenton, 0 - closed; 100 - open
+	Position int
+
+	// positionReported is set when Position was present in received object
+	positionReported bool
+}
+
+// UnmarshalJSON decodes Shutter and checks if Position is reported (update-script of older firmware has none)
+func (sh *Shutter) UnmarshalJSON(data []byte) error {
+	type shutterJSON Shutter
+	err := json.Unmarshal(data, (*shutterJSON)(sh))
+	if err != nil {
+		return err
+	}
+
+	var reported struct {
+		Position *int
+	}
+	err = json.Unmarshal(data, &reported)
+	sh.positionReported = err == nil && reported.Position != nil
+	return nil
 }
 
 func (sh *Shutter) InitAll() {
@@ -45,11 +103,65 @@ func (sh *Shutter) InitAll() {
 		Id:   sh.GetMixedId(),
 	}
 
-	sh.currentPosition = 100
-	sh.targetPosition = 100
+	position := sh.restoreState()
+	sh.Position = position
+
+	// motion is prepared also for shutters with feedback, GATE may not report position
+	sh.motion = newShutterMotion(realClock{}, position)
+	sh.motion.send = sh.drive
+	sh.motion.travelTime = sh.travelTime
+	sh.motion.overrun = time.Duration(sh.Overrun) * time.Millisecond
+	sh.motion.changed = sh.motionChanged
+	sh.motionStatus = motionStatus{Position: position, Target: position}
+	sh.estimated.Store(sh.NoFeedback)
 
 	sh.AppendHk()
+
+	sh.motion.Start()
+}
+
+func (sh *Shutter) storeKey() string {
+	return sh.clu.Id + "/" + sh.GetMixedId()
 }
+
+// restoreState loads calibrated times and returns last known position saved before restart
+func (sh *Shutter) restoreState() int {
+	state, ok := sh.clu.set.shutterStore.get(sh.storeKey())
+	if !ok {
+		return 100
+	}
+
+	sh.calibratedUpTime = state.UpTime
+	sh.calibratedDownTime = state.DownTime
+
+	position := state.Position
+	// shutter was moving when grengate stopped, it runs to the end when moving fully up or down
+	if state.State != 0 && (state.Target == 0 || state.Target == 100) {
+		position = state.Target
+	}
+
+	sh.clu.set.Debugf("Shutter %s restored position: %d\n", sh.GetMixedId(), position)
+	return position
+}
+
+// saveState persists estimated position and movement state
+func (sh *Shutter) saveState(status motionStatus) {
+	sh.block.Lock()
+	state := shutterState{
+		Position: status.Position,
+		Target:   status.Target,
+		State:    status.State,
+		UpTime:   sh.calibratedUpTime,
+		DownTime: sh.calibratedDownTime,
+	}
+	sh.block.Unlock()
+
+	err := sh.clu.set.shutterStore.save(sh.storeKey(), state)
+	if err != nil {
+		sh.clu.set.Error(err)
+	}
+}
+
 func (sh *Shutter) AppendHk() {
 	info := accessory.Info{
 		Name:         sh.Name,
@@ -61,18 +173,28 @@ func (sh *Shutter) AppendHk() {
 	sh.hk = ShutterAccessory(*accessory.NewWindowCovering(info))
 	sh.hk.A.Id = sh.GetLongId()
 
-	sh.hk.WindowCovering.CurrentPosition.SetValue(sh.currentPosition)
-	sh.hk.WindowCovering.TargetPosition.SetValue(sh.targetPosition)
-	sh.hk.WindowCovering.PositionState.SetValue(sh.GetHkState())
+	if sh.Tilt {
+		sh.hkCurrentTilt = characteristic.NewCurrentHorizontalTiltAngle()
+		sh.hkTargetTilt = characteristic.NewTargetHorizontalTiltAngle()
+		sh.hk.WindowCovering.AddC(sh.hkCurrentTilt.C)
+		sh.hk.WindowCovering.AddC(sh.hkTargetTilt.C)
+
+		sh.hkTargetTilt.OnValueRemoteUpdate(sh.SetTilt)
+		sh.guardWrites(sh.hkTargetTilt.C)
+	}
+
+	sh.Sync()
+	sh.hk.WindowCovering.TargetPosition.SetValue(sh.hk.WindowCovering.CurrentPosition.Value())
 
 	sh.hk.WindowCovering.TargetPosition.OnValueRemoteUpdate(sh.SetPosition)
+	sh.guardWrites(sh.hk.WindowCovering.TargetPosition.C)
 
 	sh.clu.set.Logf("HK WindowCovering added (id: %x)", sh.hk.A.Id)
 }
 
-// GetHkState returns windows covering state in HomeKit characteristic format
-func (sh *Shutter) GetHkState() int {
-	switch sh.State {
+// hkPositionState returns windows covering state (Grenton format) in HomeKit characteristic format
+func hkPositionState(state int) int {
+	switch state {
 	case 1:
 		return characteristic.PositionStateIncreasing
 	case 2:
@@ -82,87 +204,120 @@ func (sh *Shutter) GetHkState() int {
 	}
 }
 
-// Sync sets HK accessory values based on Shutter values
+// GetHkTilt returns slat angle in HomeKit format (-90 - 90 degrees)
+func (sh *Shutter) GetHkTilt() int {
+	return sh.Lamel*2 - 90
+}
+
+// Sync sets HK accessory values based on Shutter values, estimated position is used for shutters without feedback
 func (sh *Shutter) Sync() {
-	sh.hk.WindowCovering.CurrentPosition.SetValue(sh.currentPosition)
-	sh.hk.WindowCovering.PositionState.SetValue(sh.GetHkState())
+	sh.block.Lock()
+	defer sh.block.Unlock()
+
+	if sh.estimated.Load() {
+		sh.hk.WindowCovering.CurrentPosition.SetValue(sh.motionStatus.Position)
+		sh.hk.WindowCovering.PositionState.SetValue(hkPositionState(sh.motionStatus.State))
+	} else {
+		sh.hk.WindowCovering.CurrentPosition.SetValue(sh.Position)
+		sh.hk.WindowCovering.PositionState.SetValue(hkPositionState(sh.State))
+	}
+
+	if sh.Tilt {
+		sh.hkCurrentTilt.SetValue(sh.GetHkTilt())
+	}
 }
 
-// SetPosition check which direction should move and call StartMoving func
-func (sh *Shutter) SetPosition(target int) {
-	sh.clu.set.Debugf("Shutter SetPosition | target: %d\tcurrent: %d\told target: %d\n", target, sh.currentPosition, sh.targetPosition)
-	sh.hk.WindowCovering.TargetPosition.SetValue(target)
-	sh.targetPosition = target
+// motionChanged is called by shutterMotion when movement starts or stops
+func (sh *Shutter) motionChanged(status motionStatus) {
+	sh.clu.set.Debugf("Shutter %s motion changed: %+v\n", sh.GetMixedId(), status)
 
-	var cmd shutterCmd
+	sh.block.Lock()
+	sh.motionStatus = status
+	sh.block.Unlock()
 
-	if target == sh.currentPosition {
-		cmd = shutterStop
-	} else {
-		if target > sh.currentPosition {
-			if target == 100 {
-				sh.clu.set.Debugf("Shutter || going up and target == 100, setting current to 0")
-				sh.currentPosition = 0
-			}
-			cmd = shutterUp
-		} else {
-			if target == 0 {
-				sh.clu.set.Debugf("Shutter || going down and target == 0, setting current to 100")
-				sh.currentPosition = 100
-			}
-			cmd = shutterDown
-		}
+	sh.hk.WindowCovering.TargetPosition.SetValue(status.Target)
+	sh.Sync()
+	sh.saveState(status)
+}
+
+// SetTilt converts HomeKit slat angle to Grenton lamel position and sends it
+func (sh *Shutter) SetTilt(angle int) {
+	if sh.calibrating.Load() {
+		sh.clu.set.Error(fmt.Errorf("Shutter SetTilt: %w", errCalibrationInProgress))
+		sh.hkTargetTilt.SetValue(sh.hkCurrentTilt.Value())
+		return
 	}
 
-	sh.clu.set.Debugf("Shutter setting position cmd: %v\n", cmd)
+	sh.block.Lock()
+	sh.clu.set.Debugf("Shutter SetTilt | target: %d\tcurrent: %d\n", angle, sh.GetHkTilt())
+	sh.Lamel = (angle + 90) / 2
+	sh.block.Unlock()
 
-	err := sh.sendCmd(cmd)
+	sh.hkTargetTilt.SetValue(angle)
+
+	err := sh.sendCmd(shutterLamel)
 	if err != nil {
-		sh.clu.set.Error(fmt.Errorf("Shutter sendCmd: error from sending request:\n%v", err))
+		sh.clu.set.Error(fmt.Errorf("Shutter SetTilt: error from sending request:\n%v", err))
+		return
 	}
+	sh.Sync()
+}
 
-	period, err := time.ParseDuration(fmt.Sprintf("%dms", sh.MaxTime/100))
-	if err != nil {
-		sh.clu.set.Error(err)
+// SetPosition sends absolute position to Grenton, or for shutters without feedback
+// passes new target to shutterMotion, which can also change it during movement
+func (sh *Shutter) SetPosition(target int) {
+	sh.clu.set.Debugf("Shutter SetPosition | target: %d\n", target)
+	if sh.calibrating.Load() {
+		sh.clu.set.Error(fmt.Errorf("Shutter SetPosition: %w", errCalibrationInProgress))
+		sh.hk.WindowCovering.TargetPosition.SetValue(sh.hk.WindowCovering.CurrentPosition.Value())
 		return
 	}
-	if sh.looping || period <= 0 {
-		sh.cancelMovement <- true
+	sh.hk.WindowCovering.TargetPosition.SetValue(target)
+
+	if sh.estimated.Load() {
+		sh.motion.SetTarget(target)
 		return
 	}
-	sh.clu.set.Debugf("Shutter starting move ticker period: %s\n", period.String())
-	sh.moveTicker = time.NewTicker(period)
-	go sh.moveLoop()
-
-}
-
-func (sh *Shutter) moveLoop() {
-	sh.looping = true
-
-	for {
-		select {
-		case <-sh.cancelMovement:
-			sh.sendCmd(shutterStop)
-			sh.moveTicker.Stop()
-			sh.Sync()
-			sh.looping = false
-			return
-		case <-sh.moveTicker.C:
-			if sh.targetPosition == sh.currentPosition {
-				sh.sendCmd(shutterStop)
-				sh.moveTicker.Stop()
-				sh.Sync()
-				sh.looping = false
-				return
-			}
-			if sh.currentPosition < sh.targetPosition {
-				sh.currentPosition++
-			} else {
-				sh.currentPosition--
-			}
-		}
+
+	sh.block.Lock()
+	sh.Position = target
+	sh.block.Unlock()
+
+	err := sh.sendCmd(shutterPosition)
+	if err != nil {
+		sh.clu.set.Error(fmt.Errorf("Shutter sendCmd: error from sending request:\n%v", err))
+	}
+}
+
+// travelTime returns full travel time for given direction,
+// configured time has priority over calibrated one, MaxTime from CLU is used as a fallback
+func (sh *Shutter) travelTime(cmd shutterCmd) time.Duration {
+	sh.block.Lock()
+	defer sh.block.Unlock()
+
+	ms := sh.MaxTime
+	switch {
+	case cmd == shutterUp && sh.UpTime > 0:
+		ms = sh.UpTime
+	case cmd == shutterUp && sh.calibratedUpTime > 0:
+		ms = sh.calibratedUpTime
+	case cmd == shutterDown && sh.DownTime > 0:
+		ms = sh.DownTime
+	case cmd == shutterDown && sh.calibratedDownTime > 0:
+		ms = sh.calibratedDownTime
 	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// drive sends movement command on behalf of shutterMotion, errors are logged
+func (sh *Shutter) drive(cmd shutterCmd) error {
+	sh.clu.set.Debugf("Shutter %s drive cmd: %v\n", sh.GetMixedId(), cmd)
 
+	err := sh.sendCmd(cmd)
+	if err != nil {
+		sh.clu.set.Error(fmt.Errorf("Shutter drive: error from sending request:\n%v", err))
+	}
+	return err
 }
 
 func (sh *Shutter) sendCmd(cmd shutterCmd) error {
@@ -179,6 +334,14 @@ func (sh *Shutter) sendCmd(cmd shutterCmd) error {
 		req.Cmd = "MOVEDOWN"
 	case shutterStop:
 		req.Cmd = "STOP"
+	case shutterLamel:
+		req.Cmd = "LAMEL"
+		req.Shutter = sh.snapshot()
+	case shutterPosition:
+		req.Cmd = "POSITION"
+		req.Shutter = sh.snapshot()
+	case shutterRead:
+		req.Cmd = "READ"
 	}
 
 	// ignoring returned object - cmd endpoint not working correctly
@@ -188,6 +351,25 @@ func (sh *Shutter) sendCmd(cmd shutterCmd) error {
 	return err
 }
 
+// snapshot returns copy of values sent to GATE, request is marshalled later by GateBroker goroutine
+func (sh *Shutter) snapshot() *Shutter {
+	sh.block.Lock()
+	defer sh.block.Unlock()
+
+	return &Shutter{
+		CluObject: CluObject{Id: sh.Id, Name: sh.Name, Kind: sh.Kind},
+		State:     sh.State,
+		MaxTime:   sh.MaxTime,
+		Lamel:     sh.Lamel,
+		Position:  sh.Position,
+	}
+}
+
+// Close stops shutter moved by grengate (estimated position is saved), called on shutdown
+func (sh *Shutter) Close() {
+	sh.motion.Close()
+}
+
 // LoadReqObject checks object received from http request end reads it into Shutter
 func (sh *Shutter) LoadReqObject(obj ReqObject) error {
 	if obj.Kind != "Shutter" {
@@ -200,10 +382,129 @@ func (sh *Shutter) LoadReqObject(obj ReqObject) error {
 
 	sh.clu.set.Debugf("Shutter LoadReqObject loading: \n%+v", obj)
 
+	sh.SetFault(false)
+
+	if !obj.Shutter.positionReported && sh.estimated.CompareAndSwap(false, true) {
+		sh.clu.set.Logf("Shutter %s: position not reported by GATE, estimating it with timer (set NoFeedback in config)", sh.Name)
+		sh.block.Lock()
+		position := sh.Position
+		sh.block.Unlock()
+		sh.motion.Reset(position)
+	}
+
+	sh.block.Lock()
 	sh.State = obj.Shutter.State
 	sh.MaxTime = obj.Shutter.MaxTime
+	if sh.Tilt {
+		sh.Lamel = obj.Shutter.Lamel
+	}
+	if !sh.estimated.Load() {
+		sh.Position = obj.Shutter.Position
+		if sh.State == 0 {
+			sh.hk.WindowCovering.TargetPosition.SetValue(sh.Position)
+		}
+	}
+	sh.block.Unlock()
 
 	sh.Sync()
 
 	return nil
 }
+
+// Calibrating returns true when calibration is in progress
+func (sh *Shutter) Calibrating() bool {
+	return sh.calibrating.Load()
+}
+
+// Calibrate drives shutter fully up, then down and up again measuring travel times,
+// measured times are saved and used for position estimation.
+// Measured time is a time until CLU reports shutter stopped, so it is a real travel time only
+// when roller shutter module stops at end stop by itself. Move stopped by CLU MaxTime
+// is rejected, UpTime and DownTime have to be configured then.
+func (sh *Shutter) Calibrate() error {
+	if !sh.calibrating.CompareAndSwap(false, true) {
+		return fmt.Errorf("Shutter Calibrate: %w", errCalibrationInProgress)
+	}
+	defer sh.calibrating.Store(false)
+
+	sh.clu.set.Logf("Shutter %s calibration started", sh.Name)
+
+	if sh.estimated.Load() {
+		sh.motion.Stop()
+	}
+
+	_, err := sh.measureMove(shutterUp)
+	if err != nil {
+		return fmt.Errorf("Shutter Calibrate: moving to upper end stop failed: %w", err)
+	}
+
+	downTime, err := sh.measureMove(shutterDown)
+	if err != nil {
+		return fmt.Errorf("Shutter Calibrate: measuring down time failed: %w", err)
+	}
+
+	upTime, err := sh.measureMove(shutterUp)
+	if err != nil {
+		return fmt.Errorf("Shutter Calibrate: measuring up time failed: %w", err)
+	}
+
+	sh.block.Lock()
+	sh.calibratedDownTime = int(downTime.Milliseconds())
+	sh.calibratedUpTime = int(upTime.Milliseconds())
+	sh.block.Unlock()
+
+	// shutter is at upper end stop, Reset also saves the new times
+	if sh.estimated.Load() {
+		sh.motion.Reset(100)
+	} else {
+		sh.saveState(motionStatus{Position: 100, Target: 100})
+	}
+
+	sh.clu.set.Logf("Shutter %s calibration finished, up time: %s, down time: %s", sh.Name, upTime, downTime)
+	return nil
+}
+
+// measureMove sends move command and waits until Grenton reports shutter stopped
+func (sh *Shutter) measureMove(cmd shutterCmd) (time.Duration, error) {
+	err := sh.sendCmd(cmd)
+	if err != nil {
+		return 0, err
+	}
+	started := time.Now()
+
+	// waiting for the shutter to start, then to stop
+	moving := false
+	for time.Since(started) < calibrationTimeout {
+		time.Sleep(calibrationPollPeriod)
+
+		err = sh.sendCmd(shutterRead)
+		if err != nil {
+			return 0, err
+		}
+
+		sh.block.Lock()
+		state := sh.State
+		sh.block.Unlock()
+
+		if state != 0 {
+			moving = true
+		} else if moving {
+			return sh.checkMeasured(time.Since(started))
+		}
+	}
+
+	sh.sendCmd(shutterStop)
+	return 0, fmt.Errorf("shutter did not stop in %s", calibrationTimeout)
+}
+
+// checkMeasured rejects move time equal to CLU MaxTime, shutter was stopped by CLU timer, not by end stop
+func (sh *Shutter) checkMeasured(measured time.Duration) (time.Duration, error) {
+	sh.block.Lock()
+	maxTime := time.Duration(sh.MaxTime) * time.Millisecond
+	sh.block.Unlock()
+
+	if maxTime > 0 && measured >= maxTime-calibrationMaxTimeMargin {
+		return 0, fmt.Errorf("shutter stopped after %s by CLU MaxTime (%s), end stop not detected, set UpTime and DownTime in config", measured, maxTime)
+	}
+	return measured, nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// motionClock abstracts time for shutterMotion, so movement can be tested without waiting
+type motionClock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) motionTimer
+}
+
+type motionTimer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) motionTimer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (rt realTimer) C() <-chan time.Time {
+	return rt.Timer.C
+}
+
+// motionStatus is a snapshot of shutterMotion state, State uses Grenton format (0 - stopped; 1 - up; 2 - down)
+type motionStatus struct {
+	Position int
+	Target   int
+	State    int
+}
+
+// shutterMotion estimates position of shutter without feedback.
+// All state is owned by run goroutine, other goroutines access it only through do.
+type shutterMotion struct {
+	clock motionClock
+	// send drives the shutter with shutterUp, shutterDown or shutterStop command
+	send func(cmd shutterCmd) error
+	// travelTime returns full travel time for given direction
+	travelTime func(cmd shutterCmd) time.Duration
+	// overrun is added when moving to end stop (0 or 100)
+	overrun time.Duration
+	// changed is called from run goroutine when movement starts or stops
+	changed func(status motionStatus)
+
+	actions   chan func()
+	quit      chan struct{}
+	closeOnce sync.Once
+
+	position  float64
+	target    int
+	direction shutterCmd
+	startPos  float64
+	started   time.Time
+	arrival   time.Time
+	timer     motionTimer
+}
+
+func newShutterMotion(clock motionClock, position int) *shutterMotion {
+	return &shutterMotion{
+		clock:     clock,
+		actions:   make(chan func()),
+		quit:      make(chan struct{}),
+		position:  float64(position),
+		target:    position,
+		direction: shutterStop,
+	}
+}
+
+// Start runs the goroutine owning motion state
+func (sm *shutterMotion) Start() {
+	go sm.run()
+}
+
+// Close stops the shutter if moving and ends the owning goroutine, later calls are ignored
+func (sm *shutterMotion) Close() {
+	sm.closeOnce.Do(func() {
+		sm.do(func() {
+			if sm.moving() {
+				sm.halt()
+			}
+		})
+		close(sm.quit)
+	})
+}
+
+func (sm *shutterMotion) run() {
+	for {
+		var timerC <-chan time.Time
+		if sm.timer != nil {
+			timerC = sm.timer.C()
+		}
+
+		select {
+		case action := <-sm.actions:
+			sm.advance()
+			action()
+		case <-timerC:
+			sm.timer = nil
+			sm.advance()
+		case <-sm.quit:
+			return
+		}
+	}
+}
+
+// do executes f in run goroutine and waits for it to finish, f is not executed after Close
+func (sm *shutterMotion) do(f func()) {
+	done := make(chan struct{})
+	select {
+	case sm.actions <- func() {
+		f()
+		close(done)
+	}:
+	case <-sm.quit:
+		return
+	}
+	<-done
+}
+
+// SetTarget moves shutter to target position, it can be called during movement:
+// same direction only changes the stop time, opposite direction stops the shutter first
+func (sm *shutterMotion) SetTarget(target int) {
+	sm.do(func() {
+		sm.setTarget(target)
+	})
+}
+
+// Stop stops the shutter at current estimated position
+func (sm *shutterMotion) Stop() {
+	sm.do(func() {
+		sm.target = sm.Position()
+		if sm.moving() {
+			sm.halt()
+		}
+	})
+}
+
+// Reset sets known position (ex. after calibration), shutter has to be stopped
+func (sm *shutterMotion) Reset(position int) {
+	sm.do(func() {
+		sm.position = float64(position)
+		sm.target = position
+		sm.changed(sm.status())
+	})
+}
+
+// Status returns current state with estimated position
+func (sm *shutterMotion) Status() (status motionStatus) {
+	sm.do(func() {
+		status = sm.status()
+	})
+	return
+}
+
+func (sm *shutterMotion) Position() int {
+	return int(sm.position + 0.5)
+}
+
+func (sm *shutterMotion) moving() bool {
+	return sm.direction != shutterStop
+}
+
+func (sm *shutterMotion) status() motionStatus {
+	status := motionStatus{
+		Position: sm.Position(),
+		Target:   sm.target,
+	}
+	switch sm.direction {
+	case shutterUp:
+		status.State = 1
+	case shutterDown:
+		status.State = 2
+	}
+	return status
+}
+
+// advance updates estimated position and stops the shutter when arrival time passed
+func (sm *shutterMotion) advance() {
+	if !sm.moving() {
+		return
+	}
+
+	now := sm.clock.Now()
+	if !now.Before(sm.arrival) {
+		sm.position = float64(sm.target)
+		sm.halt()
+		return
+	}
+
+	travel := sm.travelTime(sm.direction)
+	if travel <= 0 {
+		return
+	}
+	moved := float64(now.Sub(sm.started)) / float64(travel) * 100
+	if sm.direction == shutterUp {
+		sm.position = min(sm.startPos+moved, float64(sm.target))
+	} else {
+		sm.position = max(sm.startPos-moved, float64(sm.target))
+	}
+}
+
+func (sm *shutterMotion) setTarget(target int) {
+	sm.target = target
+
+	var direction shutterCmd
+	switch {
+	case float64(target) > sm.position:
+		direction = shutterUp
+	case float64(target) < sm.position:
+		direction = shutterDown
+	default:
+		if sm.moving() {
+			sm.halt()
+		}
+		return
+	}
+
+	travel := sm.travelTime(direction)
+	if travel <= 0 {
+		// travel time not known yet, position can not be estimated
+		sm.position = float64(target)
+		if sm.moving() {
+			sm.halt()
+		}
+		return
+	}
+
+	if sm.moving() && sm.direction != direction {
+		sm.halt()
+	}
+
+	if sm.direction != direction {
+		if sm.send(direction) != nil {
+			// shutter did not start, HomeKit target is set back to current position
+			sm.target = sm.Position()
+			sm.changed(sm.status())
+			return
+		}
+	}
+
+	sm.direction = direction
+	sm.startPos = sm.position
+	sm.started = sm.clock.Now()
+
+	distance := float64(target) - sm.position
+	if distance < 0 {
+		distance = -distance
+	}
+	duration := time.Duration(distance / 100 * float64(travel))
+	if target == 0 || target == 100 {
+		duration += sm.overrun
+	}
+	sm.arrival = sm.started.Add(duration)
+	sm.startTimer(duration)
+
+	sm.changed(sm.status())
+}
+
+func (sm *shutterMotion) startTimer(d time.Duration) {
+	if sm.timer != nil {
+		sm.timer.Stop()
+	}
+	sm.timer = sm.clock.NewTimer(d)
+}
+
+// halt sends stop command and stops timer, estimated position is kept
+func (sm *shutterMotion) halt() {
+	if sm.timer != nil {
+		sm.timer.Stop()
+		sm.timer = nil
+	}
+	sm.direction = shutterStop
+	sm.send(shutterStop)
+	sm.changed(sm.status())
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock    *fakeClock
+	c        chan time.Time
+	deadline time.Time
+	stopped  bool
+}
+
+func (ft *fakeTimer) C() <-chan time.Time {
+	return ft.c
+}
+
+func (ft *fakeTimer) Stop() bool {
+	ft.clock.mu.Lock()
+	defer ft.clock.mu.Unlock()
+
+	active := !ft.stopped
+	ft.stopped = true
+	return active
+}
+
+func (fc *fakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+func (fc *fakeClock) NewTimer(d time.Duration) motionTimer {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	ft := &fakeTimer{clock: fc, c: make(chan time.Time, 1), deadline: fc.now.Add(d)}
+	fc.timers = append(fc.timers, ft)
+	return ft
+}
+
+// Advance moves clock forward and fires expired timers
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.now = fc.now.Add(d)
+	pending := fc.timers[:0]
+	for _, ft := range fc.timers {
+		if ft.stopped {
+			continue
+		}
+		if !fc.now.Before(ft.deadline) {
+			ft.stopped = true
+			ft.c <- fc.now
+			continue
+		}
+		pending = append(pending, ft)
+	}
+	fc.timers = pending
+}
+
+type motionRecorder struct {
+	mu       sync.Mutex
+	cmds     []shutterCmd
+	statuses []motionStatus
+	// failing commands are recorded, but send returns an error for them
+	failing []shutterCmd
+}
+
+var errTestSend = errors.New("test send failed")
+
+func (mr *motionRecorder) send(cmd shutterCmd) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	mr.cmds = append(mr.cmds, cmd)
+	for _, failing := range mr.failing {
+		if cmd == failing {
+			return errTestSend
+		}
+	}
+	return nil
+}
+
+func (mr *motionRecorder) changed(status motionStatus) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	mr.statuses = append(mr.statuses, status)
+}
+
+func (mr *motionRecorder) setFailing(cmds ...shutterCmd) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	mr.failing = cmds
+}
+
+func (mr *motionRecorder) lastChanged() (motionStatus, bool) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if len(mr.statuses) == 0 {
+		return motionStatus{}, false
+	}
+	return mr.statuses[len(mr.statuses)-1], true
+}
+
+func (mr *motionRecorder) commands() []shutterCmd {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return append([]shutterCmd{}, mr.cmds...)
+}
+
+func newTestMotion(t *testing.T, position int, up, down, overrun time.Duration) (*shutterMotion, *fakeClock, *motionRecorder) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	rec := &motionRecorder{}
+
+	sm := newShutterMotion(clock, position)
+	sm.send = rec.send
+	sm.changed = rec.changed
+	sm.overrun = overrun
+	sm.travelTime = func(cmd shutterCmd) time.Duration {
+		if cmd == shutterUp {
+			return up
+		}
+		return down
+	}
+	sm.Start()
+	t.Cleanup(sm.Close)
+
+	return sm, clock, rec
+}
+
+func checkStatus(t *testing.T, sm *shutterMotion, want motionStatus) {
+	t.Helper()
+	if got := sm.Status(); got != want {
+		t.Fatalf("status = %+v, want %+v", got, want)
+	}
+}
+
+func checkCommands(t *testing.T, rec *motionRecorder, want ...shutterCmd) {
+	t.Helper()
+	if got := rec.commands(); !reflect.DeepEqual(got, append([]shutterCmd{}, want...)) {
+		t.Fatalf("commands = %v, want %v", got, want)
+	}
+}
+
+func TestShutterMotionMovesToTarget(t *testing.T) {
+	sm, clock, rec := newTestMotion(t, 100, 20*time.Second, 10*time.Second, 0)
+
+	sm.SetTarget(40)
+	checkCommands(t, rec, shutterDown)
+	checkStatus(t, sm, motionStatus{Position: 100, Target: 40, State: 2})
+
+	clock.Advance(3 * time.Second)
+	checkStatus(t, sm, motionStatus{Position: 70, Target: 40, State: 2})
+
+	clock.Advance(3 * time.Second)
+	checkStatus(t, sm, motionStatus{Position: 40, Target: 40, State: 0})
+	checkCommands(t, rec, shutterDown, shutterStop)
+}
+
+func TestShutterMotionUsesDirectionTravelTime(t *testing.T) {
+	sm, clock, rec := newTestMotion(t, 0, 20*time.Second, 10*time.Second, 0)
+
+	sm.SetTarget(50)
+	clock.Advance(5 * time.Second)
+	checkStatus(t, sm, motionStatus{Position: 25, Target: 50, State: 1})
+
+	clock.Advance(5 * time.Second)
+	checkStatus(t, sm, motionStatus{Position: 50, Target: 50, State: 0})
+	checkCommands(t, rec, shutterUp, shutterStop)
+}
+
+func TestShutterMotionChangesTargetInSameDirection(t *testing.T) {
+	sm, clock, rec := newTestMotion(t, 100, 10*time.Second, 10*time.Second, 0)
+
+	sm.SetTarget(20)
+	clock.Advance(2 * time.Second)
+	sm.SetTarget(60)
+	// already moving down, no new command is needed
+	checkCommands(t, rec, shutterDown)
+	checkStatus(t, sm, motionStatus{Position: 80, Target: 60, State: 2})
+
+	clock.Advance(2 * time.Second)
+	checkStatus(t, sm, motionStatus{Position: 60, Target: 60, State: 0})
+	checkCommands(t, rec, shutterDown, shutterStop)
+}
+
+func TestShutterMotionReversesDirection(t *testing.T) {
+	sm, clock, rec := newTestMotion(t, 100, 10*time.Second, 10*time.Second, 0)
+
+	sm.SetTarget(0)
+	clock.Advance(5 * time.Second)
+	sm.SetTarget(80)
+	checkCommands(t, rec, shutterDown, shutterStop, shutterUp)
+	checkStatus(t, sm, motionStatus{Position: 50, Target: 80, State: 1})
+
+	clock.Advance(2 * time.Second)
+	checkStatus(t, sm, motionStatus{Position: 70, Target: 80, State: 1})
+
+	clock.Advance(time.Second)
+	checkStatus(t, sm, motionStatus{Position: 80, Target: 80, State: 0})
+	checkCommands(t, rec, shutterDown, shutterStop, shutterUp, shutterStop)
+}
+
+func TestShutterMotionTargetReachedStops(t *testing.T) {
+	sm, clock, rec := newTestMotion(t, 100, 10*time.Second, 10*time.Second, 0)
+
+	sm.SetTarget(100)
+	checkCommands(t, rec)
+
+	sm.SetTarget(0)
+	clock.Advance(4 * time.Second)
+	sm.SetTarget(60)
+	checkCommands(t, rec, shutterDown, shutterStop)
+	checkStatus(t, sm, motionStatus{Position: 60, Target: 60, State: 0})
+}
+
+func TestShutterMotionOverrunAtEndStop(t *testing.T) {
+	sm, clock, rec := newTestMotion(t, 50, 10*time.Second, 10*time.Second, 2*time.Second)
+
+	sm.SetTarget(100)
+	clock.Advance(5 * time.Second)
+	// position reached, but shutter keeps moving to be sure it is at end stop
+	checkStatus(t, sm, motionStatus{Position: 100, Target: 100, State: 1})
+
+	clock.Advance(2 * time.Second)
+	checkStatus(t, sm, motionStatus{Position: 100, Target: 100, State: 0})
+	checkCommands(t, rec, shutterUp, shutterStop)
+}
+
+func TestShutterMotionStopsOnTimer(t *testing.T) {
+	sm, clock, rec := newTestMotion(t, 100, 10*time.Second, 10*time.Second, 0)
+
+	sm.SetTarget(90)
+	clock.Advance(time.Second)
+
+	// no call to shutterMotion, stop is triggered by the timer only
+	deadline := time.Now().Add(time.Second)
+	for len(rec.commands()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	checkCommands(t, rec, shutterDown, shutterStop)
+
+	rec.mu.Lock()
+	last := rec.statuses[len(rec.statuses)-1]
+	rec.mu.Unlock()
+	if last != (motionStatus{Position: 90, Target: 90}) {
+		t.Fatalf("last reported status = %+v, want stopped at 90", last)
+	}
+}
+
+func TestShutterMotionStop(t *testing.T) {
+	sm, clock, rec := newTestMotion(t, 0, 10*time.Second, 10*time.Second, 0)
+
+	sm.SetTarget(100)
+	clock.Advance(3 * time.Second)
+	sm.Stop()
+	checkCommands(t, rec, shutterUp, shutterStop)
+	checkStatus(t, sm, motionStatus{Position: 30, Target: 30, State: 0})
+}
+
+func TestShutterMotionClose(t *testing.T) {
+	sm, clock, rec := newTestMotion(t, 0, 10*time.Second, 10*time.Second, 0)
+
+	sm.SetTarget(100)
+	clock.Advance(4 * time.Second)
+	sm.Close()
+	checkCommands(t, rec, shutterUp, shutterStop)
+
+	// closed motion ignores commands instead of blocking
+	sm.SetTarget(0)
+	sm.Close()
+	checkCommands(t, rec, shutterUp, shutterStop)
+}
+
+func TestShutterMotionSendFailureRestoresTarget(t *testing.T) {
+	sm, clock, rec := newTestMotion(t, 100, 20*time.Second, 10*time.Second, 0)
+	rec.setFailing(shutterDown)
+
+	sm.SetTarget(40)
+	checkCommands(t, rec, shutterDown)
+	checkStatus(t, sm, motionStatus{Position: 100, Target: 100})
+	if got, ok := rec.lastChanged(); !ok || got != (motionStatus{Position: 100, Target: 100}) {
+		t.Fatalf("changed status = %+v (published: %v), want target restored to position", got, ok)
+	}
+
+	// no timer was started, shutter stays where it was
+	clock.Advance(10 * time.Second)
+	checkStatus(t, sm, motionStatus{Position: 100, Target: 100})
+
+	// next command is sent again
+	rec.setFailing()
+	sm.SetTarget(40)
+	checkCommands(t, rec, shutterDown, shutterDown)
+	checkStatus(t, sm, motionStatus{Position: 100, Target: 40, State: 2})
+}
+
+func TestShutterMotionSendFailureOnReverse(t *testing.T) {
+	sm, clock, rec := newTestMotion(t, 100, 20*time.Second, 10*time.Second, 0)
+
+	sm.SetTarget(0)
+	clock.Advance(5 * time.Second)
+	rec.setFailing(shutterUp)
+
+	// shutter is stopped before reversing, failed start leaves it stopped at current position
+	sm.SetTarget(100)
+	checkCommands(t, rec, shutterDown, shutterStop, shutterUp)
+	checkStatus(t, sm, motionStatus{Position: 50, Target: 50})
+	if got, _ := rec.lastChanged(); got != (motionStatus{Position: 50, Target: 50}) {
+		t.Fatalf("changed status = %+v, want stopped at 50", got)
+	}
+}
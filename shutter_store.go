@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// shutterState is a persisted shutter position, movement state and calibrated travel times
+type shutterState struct {
+	Position int
+	Target   int
+	State    int
+
+	UpTime   int `json:",omitempty"`
+	DownTime int `json:",omitempty"`
+}
+
+// shutterStore keeps last known shutter positions in json file, so they survive restarts
+type shutterStore struct {
+	path   string
+	states map[string]shutterState
+	block  sync.Mutex
+}
+
+func newShutterStore(path string) *shutterStore {
+	return &shutterStore{
+		path:   path,
+		states: map[string]shutterState{},
+	}
+}
+
+// load reads state file, missing file is not an error (first run)
+func (ss *shutterStore) load() error {
+	ss.block.Lock()
+	defer ss.block.Unlock()
+
+	data, err := os.ReadFile(ss.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("shutterStore load: reading file failed: %w", err)
+	}
+
+	err = json.Unmarshal(data, &ss.states)
+	if err != nil {
+		return fmt.Errorf("shutterStore load: json unmarshal failed: %w", err)
+	}
+	return nil
+}
+
+func (ss *shutterStore) get(key string) (state shutterState, ok bool) {
+	ss.block.Lock()
+	defer ss.block.Unlock()
+
+	state, ok = ss.states[key]
+	return
+}
+
+// save updates shutter state and writes the file atomically (temp file renamed over the old one)
+func (ss *shutterStore) save(key string, state shutterState) error {
+	ss.block.Lock()
+	defer ss.block.Unlock()
+
+	ss.states[key] = state
+
+	data, err := json.MarshalIndent(ss.states, "", "\t")
+	if err != nil {
+		return fmt.Errorf("shutterStore save: json marshal failed: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(ss.path), filepath.Base(ss.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("shutterStore save: creating temp file failed: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("shutterStore save: writing temp file failed: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), ss.path)
+	if err != nil {
+		return fmt.Errorf("shutterStore save: renaming temp file failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestShutterPositionReported(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		reported bool
+		position int
+	}{
+		{"position reported", `{"Kind": "Shutter", "Shutter": {"State": 0, "MaxTime": 30000, "Position": 40}}`, true, 40},
+		{"closed shutter reported", `{"Kind": "Shutter", "Shutter": {"State": 0, "Position": 0}}`, true, 0},
+		{"older firmware without position", `{"Kind": "Shutter", "Shutter": {"State": 1, "MaxTime": 30000}}`, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := ReqObject{}
+			if err := json.Unmarshal([]byte(tt.data), &obj); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if obj.Shutter == nil {
+				t.Fatal("Shutter object not decoded")
+			}
+			if obj.Shutter.positionReported != tt.reported || obj.Shutter.Position != tt.position {
+				t.Errorf("position = %d (reported: %v), want %d (reported: %v)", obj.Shutter.Position, obj.Shutter.positionReported, tt.position, tt.reported)
+			}
+		})
+	}
+}
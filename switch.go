@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+)
+
+type Switch struct {
+	CluObject
+
+	// Outlet is a config option, presents switch as HomeKit Outlet instead of Switch
+	Outlet bool `json:",omitempty"`
+
+	State bool
+
+	block sync.Mutex
+
+	hkAccessory *accessory.A
+	hkOn        *characteristic.On
+	hkInUse     *characteristic.OutletInUse
+}
+
+func (sw *Switch) LoadReqObject(obj ReqObject) error {
+	if obj.Kind != "Switch" {
+		return fmt.Errorf("Switch LoadReqObject: wrong object kind (%s)", obj.Kind)
+	}
+
+	if obj.Switch == nil {
+		return fmt.Errorf("Switch LoadReqObject: missing Switch object")
+	}
+
+	sw.SetFault(false)
+
+	sw.block.Lock()
+	sw.State = obj.Switch.State
+	sw.Sync()
+	sw.block.Unlock()
+
+	return nil
+}
+
+func (sw *Switch) InitAll() {
+	sw.Req = ReqObject{
+		Kind: "Switch",
+		Clu:  sw.clu.Id,
+		Id:   sw.GetMixedId(),
+	}
+	sw.AppendHk()
+}
+
+func (sw *Switch) GetA() *accessory.A {
+	return sw.hkAccessory
+}
+
+func (sw *Switch) AppendHk() *accessory.A {
+	info := accessory.Info{
+		Name:         sw.Name,
+		SerialNumber: fmt.Sprintf("%d", sw.Id),
+		Manufacturer: "Grenton",
+		Model:        sw.Kind,
+	}
+
+	if sw.Outlet {
+		hk := accessory.NewOutlet(info)
+		sw.hkAccessory = hk.A
+		sw.hkOn = hk.Outlet.On
+		sw.hkInUse = hk.Outlet.OutletInUse
+	} else {
+		hk := accessory.NewSwitch(info)
+		sw.hkAccessory = hk.A
+		sw.hkOn = hk.Switch.On
+	}
+	sw.hkAccessory.Id = sw.GetLongId()
+
+	sw.hkOn.OnValueRemoteUpdate(sw.Set)
+	sw.guardWrites(sw.hkOn.C)
+
+	sw.clu.set.Logf("HK Switch added (id: %x, type: %d, outlet: %v)", sw.hkAccessory.Id, sw.hkAccessory.Type, sw.Outlet)
+	return sw.hkAccessory
+}
+
+func (sw *Switch) Sync() {
+	sw.hkOn.SetValue(sw.State)
+	if sw.hkInUse != nil {
+		sw.hkInUse.SetValue(sw.State)
+	}
+}
+
+func (sw *Switch) Set(state bool) {
+	sw.block.Lock()
+	sw.State = state
+	// copy of sent values, request is marshalled later by GateBroker goroutine
+	req := sw.Req
+	req.Switch = &Switch{CluObject: CluObject{Id: sw.Id, Name: sw.Name, Kind: sw.Kind}, State: sw.State}
+	sw.block.Unlock()
+
+	_, err := sw.SendReq(req)
+
+	if err != nil {
+		sw.clu.set.Error(err)
+	}
+}
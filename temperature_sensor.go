@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/service"
+)
+
+// TemperatureSensor reads value from Grenton sensor feature or from user variable (when Source is set)
+type TemperatureSensor struct {
+	SensorObject
+
+	Source string `json:",omitempty"`
+
+	Value float64
+
+	hkAccessory *accessory.A
+	hkService   *service.TemperatureSensor
+}
+
+func (ts *TemperatureSensor) Init(clu *Clu) *accessory.A {
+	ts.clu = clu
+
+	ts.Req = ReqObject{
+		Kind:   "TemperatureSensor",
+		Clu:    ts.clu.Id,
+		Id:     ts.GetMixedId(),
+		Source: ts.Source,
+	}
+	return ts.appendHk()
+}
+
+func (ts *TemperatureSensor) GetA() *accessory.A {
+	return ts.hkAccessory
+}
+
+func (ts *TemperatureSensor) appendHk() *accessory.A {
+	info := accessory.Info{
+		Name:         ts.Name,
+		SerialNumber: fmt.Sprintf("%d", ts.Id),
+		Manufacturer: "Grenton",
+		Model:        ts.Kind,
+	}
+
+	ts.hkAccessory = accessory.New(info, accessory.TypeSensor)
+	ts.hkAccessory.Id = ts.GetLongId()
+
+	ts.hkService = service.NewTemperatureSensor()
+	// default minimum is 0, outdoor sensors go below
+	ts.hkService.CurrentTemperature.SetMinValue(-50)
+	ts.addFault(ts.hkService.S)
+	ts.hkAccessory.AddS(ts.hkService.S)
+
+	ts.clu.set.Logf("HK TemperatureSensor added (id: %x)", ts.hkAccessory.Id)
+
+	return ts.hkAccessory
+}
+
+func (ts *TemperatureSensor) Sync() {
+	ts.hkService.CurrentTemperature.SetValue(ts.Value)
+}
+
+// LoadReqObject checks object received from http request end reads it into TemperatureSensor
+func (ts *TemperatureSensor) LoadReqObject(obj ReqObject) error {
+	if err := ts.checkLoaded(obj, "TemperatureSensor", obj.TemperatureSensor == nil); err != nil {
+		return err
+	}
+
+	ts.Value = obj.TemperatureSensor.Value
+	ts.Sync()
+
+	return nil
+}
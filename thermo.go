@@ -2,17 +2,65 @@ package main
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+	"github.com/brutella/hap/service"
 )
 
+// thermoStates are names of HomeKit target heating cooling states, index is HomeKit characteristic value
+var thermoStates = []string{"off", "heat", "cool", "auto"}
+
+// ThermoMode is a set of Grenton thermostat State, Mode and Direction (0 - heating; 1 - cooling) values
+type ThermoMode struct {
+	State     int
+	Mode      int
+	Direction int `json:",omitempty"`
+}
+
+// defaultThermoModes: manual mode is shown as heat, schedule as auto, cooling is not available
+var defaultThermoModes = map[string]ThermoMode{
+	"off":     {State: 0},
+	"heat":    {State: 1, Mode: 0},
+	"auto":    {State: 1, Mode: 1},
+	"holiday": {State: 1, Mode: 2},
+}
+
 type Thermo struct {
 	CluObject
 
 	Source string
 
+	// Modes is a config option, maps HomeKit target states (off, heat, cool, auto) and holiday to Grenton State and Mode,
+	// missing entries are filled with defaults, HomeKit states without entry can not be selected, holiday is shown as auto
+	Modes map[string]ThermoMode `json:",omitempty"`
+	// HeaterCooler is a config option, thermostat is exposed as HomeKit heater cooler with separate thresholds
+	HeaterCooler bool `json:",omitempty"`
+	// Holiday is a config option, adds switch toggling holiday (eco) mode mapped in Modes
+	Holiday bool `json:",omitempty"`
+	// HolidayTemp is a config option, holiday temperature set on CLU when holiday mode is switched on
+	HolidayTemp float64 `json:",omitempty"`
+	// Schedule is a config option, adds contact sensor closed when thermostat follows CLU schedule
+	Schedule bool `json:",omitempty"`
+
+	targetState int
+	holiday     bool
+	// lastTarget is the last target state other than off, restored when heater cooler is activated
+	lastTarget       int
+	heatingThreshold float64
+	coolingThreshold float64
+	block            sync.Mutex
+
 	hk *accessory.Thermostat `json:"-"`
 
+	hkAccessory    *accessory.A
+	hkHeaterCooler *service.HeaterCooler
+	hkHeating      *characteristic.HeatingThresholdTemperature
+	hkCooling      *characteristic.CoolingThresholdTemperature
+	hkHoliday      *service.Switch
+	hkSchedule     *service.ContactSensor
+
 	TempCurrent,
 	TempSetpoint,
 	TempTarget,
@@ -21,12 +69,37 @@ type Thermo struct {
 	TempMin float64
 
 	Mode,
-	State int
+	State,
+	Direction int
 }
 
-func (gt *Thermo) GetHkState() (hkState int) {
-	hkState = gt.State
-	return
+// GetHkState returns current heating cooling state in HomeKit characteristic format
+func (gt *Thermo) GetHkState() int {
+	switch gt.targetState {
+	case characteristic.TargetHeatingCoolingStateOff:
+		return characteristic.CurrentHeatingCoolingStateOff
+	case characteristic.TargetHeatingCoolingStateCool:
+		return characteristic.CurrentHeatingCoolingStateCool
+	default:
+		return characteristic.CurrentHeatingCoolingStateHeat
+	}
+}
+
+// hkTarget returns HomeKit target state matching Grenton thermostat values, false if they are not mapped
+func (gt *Thermo) hkTarget(current ThermoMode) (int, bool) {
+	if off, ok := gt.Modes["off"]; ok && off.State == current.State {
+		return characteristic.TargetHeatingCoolingStateOff, true
+	}
+
+	for hkState, name := range thermoStates {
+		if m, ok := gt.Modes[name]; ok && m == current {
+			return hkState, true
+		}
+	}
+	if m, ok := gt.Modes["holiday"]; ok && m == current {
+		return characteristic.TargetHeatingCoolingStateAuto, true
+	}
+	return 0, false
 }
 
 func (gt *Thermo) LoadReqObject(obj ReqObject) error {
@@ -40,6 +113,11 @@ func (gt *Thermo) LoadReqObject(obj ReqObject) error {
 
 	gt.clu.set.Debugf("Thermo LoadReqObject loading: \n%+v", obj)
 
+	gt.SetFault(false)
+
+	gt.block.Lock()
+	defer gt.block.Unlock()
+
 	gt.TempCurrent = obj.Thermo.TempCurrent
 	gt.TempSetpoint = obj.Thermo.TempSetpoint
 	gt.TempTarget = obj.Thermo.TempTarget
@@ -48,6 +126,27 @@ func (gt *Thermo) LoadReqObject(obj ReqObject) error {
 	gt.TempHoliday = obj.Thermo.TempHoliday
 	gt.State = obj.Thermo.State
 	gt.Mode = obj.Thermo.Mode
+	gt.Direction = obj.Thermo.Direction
+
+	current := ThermoMode{State: gt.State, Mode: gt.Mode, Direction: gt.Direction}
+	gt.holiday = current == gt.Modes["holiday"]
+	target, ok := gt.hkTarget(current)
+	if !ok {
+		gt.clu.set.Logf("Thermo %s: %+v is not mapped to HomeKit state", gt.Name, current)
+	} else {
+		gt.targetState = target
+		// state before holiday mode is restored when it ends
+		if target != characteristic.TargetHeatingCoolingStateOff && !gt.holiday {
+			gt.lastTarget = target
+		}
+	}
+
+	// setpoint is shared by both directions, it is kept as threshold of the current one
+	if gt.Direction == 0 {
+		gt.heatingThreshold = gt.TempSetpoint
+	} else {
+		gt.coolingThreshold = gt.TempSetpoint
+	}
 
 	gt.Sync()
 
@@ -61,8 +160,33 @@ func (gt *Thermo) InitAll() {
 		Id:     gt.GetMixedId(),
 		Source: gt.Source,
 	}
+
+	gt.initModes()
+	gt.lastTarget = characteristic.TargetHeatingCoolingStateHeat
+
+	if gt.HeaterCooler {
+		gt.appendHeaterCooler()
+		return
+	}
 	gt.AppendHk()
 }
+
+// initModes fills modes missing in config with defaults
+func (gt *Thermo) initModes() {
+	if gt.Modes == nil {
+		gt.Modes = map[string]ThermoMode{}
+	}
+	for name, mode := range defaultThermoModes {
+		if _, ok := gt.Modes[name]; !ok {
+			gt.Modes[name] = mode
+		}
+	}
+}
+
+func (gt *Thermo) GetA() *accessory.A {
+	return gt.hkAccessory
+}
+
 func (gt *Thermo) AppendHk() *accessory.Thermostat {
 	info := accessory.Info{
 		Name:         gt.Name,
@@ -73,9 +197,23 @@ func (gt *Thermo) AppendHk() *accessory.Thermostat {
 
 	gt.hk = accessory.NewThermostat(info)
 	gt.hk.Id = gt.GetLongId()
+	gt.hkAccessory = gt.hk.A
+
+	// only mapped target states can be selected
+	validStates := []int{}
+	for hkState, name := range thermoStates {
+		if _, ok := gt.Modes[name]; ok {
+			validStates = append(validStates, hkState)
+		}
+	}
+	gt.hk.Thermostat.TargetHeatingCoolingState.ValidVals = validStates
+
+	gt.appendHoliday()
+	gt.appendSchedule()
 
 	gt.hk.Thermostat.TargetHeatingCoolingState.OnValueRemoteUpdate(gt.SetState)
 	gt.hk.Thermostat.TargetTemperature.OnValueRemoteUpdate(gt.SetTemperature)
+	gt.guardWrites(gt.hk.Thermostat.TargetHeatingCoolingState.C, gt.hk.Thermostat.TargetTemperature.C)
 	// gt.hk.Thermostat.CurrentTemperature.OnValueRemoteGet(gt.GetTemperature)
 	// gt.hk.Thermostat.CurrentHeatingCoolingState.OnValueRemoteGet(gt.GetState)
 
@@ -83,12 +221,72 @@ func (gt *Thermo) AppendHk() *accessory.Thermostat {
 	return gt.hk
 }
 
+// appendHoliday adds holiday mode switch to thermostat accessory
+func (gt *Thermo) appendHoliday() {
+	if !gt.Holiday {
+		return
+	}
+
+	gt.hkHoliday = service.NewSwitch()
+	name := characteristic.NewName()
+	name.SetValue(gt.Name + " Holiday")
+	gt.hkHoliday.AddC(name.C)
+	gt.hkAccessory.AddS(gt.hkHoliday.S)
+
+	gt.hkHoliday.On.OnValueRemoteUpdate(gt.SetHoliday)
+	gt.guardWrites(gt.hkHoliday.On.C)
+}
+
+// appendSchedule adds read-only schedule indicator (contact sensor) to thermostat accessory,
+// Home app does not show ProgramMode characteristic of thermostat service
+func (gt *Thermo) appendSchedule() {
+	if !gt.Schedule {
+		return
+	}
+
+	gt.hkSchedule = service.NewContactSensor()
+	name := characteristic.NewName()
+	name.SetValue(gt.Name + " Schedule")
+	gt.hkSchedule.AddC(name.C)
+	gt.hkAccessory.AddS(gt.hkSchedule.S)
+}
+
+// followsSchedule returns true when thermostat follows CLU schedule (auto), false when it is off or overridden
+func (gt *Thermo) followsSchedule() bool {
+	return gt.targetState == characteristic.TargetHeatingCoolingStateAuto && !gt.holiday
+}
+
+// syncProgram sets HK schedule indicator and holiday switch, used by both thermostat and heater cooler
+func (gt *Thermo) syncProgram() {
+	if gt.hkSchedule != nil {
+		state := characteristic.ContactSensorStateContactNotDetected
+		if gt.followsSchedule() {
+			state = characteristic.ContactSensorStateContactDetected
+		}
+		gt.hkSchedule.ContactSensorState.SetValue(state)
+	}
+	if gt.hkHoliday != nil {
+		gt.hkHoliday.On.SetValue(gt.holiday)
+	}
+}
+
 func (gt *Thermo) Sync() {
+	gt.syncProgram()
+	if gt.hkHeaterCooler != nil {
+		gt.syncHeaterCooler()
+		return
+	}
+
+	// target temperature range is set on CLU, values outside of it would be clamped by HomeKit
+	if gt.TempMax > gt.TempMin {
+		gt.hk.Thermostat.TargetTemperature.SetMinValue(gt.TempMin)
+		gt.hk.Thermostat.TargetTemperature.SetMaxValue(gt.TempMax)
+	}
 
 	gt.hk.Thermostat.CurrentTemperature.SetValue(gt.TempCurrent)
 	gt.hk.Thermostat.TargetTemperature.SetValue(gt.TempTarget)
 	gt.hk.Thermostat.CurrentHeatingCoolingState.SetValue(gt.GetHkState())
-
+	gt.hk.Thermostat.TargetHeatingCoolingState.SetValue(gt.targetState)
 }
 
 func (gt *Thermo) GetTemperature() float64 {
@@ -123,38 +321,110 @@ func (gt *Thermo) GetState() int {
 
 func (gt *Thermo) SetTemperature(temp float64) {
 	gt.hk.Thermostat.TargetTemperature.SetValue(temp)
+	gt.block.Lock()
 	gt.TempSetpoint = temp
+	gt.block.Unlock()
 
-	req := gt.Req
-	req.Thermo = gt
-	obj, err := gt.SendReq(req)
-
+	gt.send("SetTemperature")
+}
+func (gt *Thermo) SetState(state int) {
+	gt.block.Lock()
+	err := gt.applyTarget(state)
+	gt.block.Unlock()
 	if err != nil {
-		gt.clu.set.Error(fmt.Errorf("Thermo SetTemperature: %w", err))
+		gt.clu.set.Error(fmt.Errorf("Thermo SetState: %w", err))
 		return
 	}
+	gt.hk.Thermostat.TargetHeatingCoolingState.SetValue(state)
 
-	gt.LoadReqObject(obj)
+	gt.send("SetState")
 }
-func (gt *Thermo) SetState(state int) {
-	gt.hk.Thermostat.TargetHeatingCoolingState.SetValue(state)
-	switch state {
-	case 1:
-		gt.State = 1
-		gt.Mode = 0
-	case 3:
-		gt.State = 1
-		gt.Mode = 1
-	default:
-		gt.State = 0
+
+// applyTarget sets Grenton values mapped to HomeKit target heating cooling state, lock must be held
+func (gt *Thermo) applyTarget(state int) error {
+	if state < 0 || state >= len(thermoStates) {
+		return fmt.Errorf("invalid target state (%d)", state)
+	}
+	mode, ok := gt.Modes[thermoStates[state]]
+	if !ok {
+		return fmt.Errorf("target state %s is not mapped", thermoStates[state])
 	}
 
+	gt.targetState = state
+	gt.holiday = false
+	gt.State = mode.State
+	// mode and direction are kept when switching off, thermostat starts in the same mode
+	if state == characteristic.TargetHeatingCoolingStateOff {
+		return nil
+	}
+	gt.lastTarget = state
+	gt.Mode = mode.Mode
+	gt.Direction = mode.Direction
+
+	// heater cooler keeps separate thresholds, setpoint follows the direction
+	if gt.HeaterCooler {
+		if gt.Direction == 0 && gt.heatingThreshold > 0 {
+			gt.TempSetpoint = gt.heatingThreshold
+		}
+		if gt.Direction != 0 && gt.coolingThreshold > 0 {
+			gt.TempSetpoint = gt.coolingThreshold
+		}
+	}
+	return nil
+}
+
+// SetHoliday switches holiday (eco) mode on, or returns to the last target state
+func (gt *Thermo) SetHoliday(on bool) {
+	gt.block.Lock()
+	if on {
+		gt.applyHoliday()
+	} else {
+		err := gt.applyTarget(gt.lastTarget)
+		if err != nil {
+			gt.block.Unlock()
+			gt.clu.set.Error(fmt.Errorf("Thermo SetHoliday: %w", err))
+			return
+		}
+	}
+	gt.Sync()
+	gt.block.Unlock()
+
+	gt.send("SetHoliday")
+}
+
+// applyHoliday sets Grenton values mapped to holiday mode, HomeKit shows it as auto, lock must be held
+func (gt *Thermo) applyHoliday() {
+	mode := gt.Modes["holiday"]
+
+	gt.holiday = true
+	gt.targetState = characteristic.TargetHeatingCoolingStateAuto
+	gt.State = mode.State
+	gt.Mode = mode.Mode
+	gt.Direction = mode.Direction
+	if gt.HolidayTemp > 0 {
+		gt.TempHoliday = gt.HolidayTemp
+	}
+}
+
+func (gt *Thermo) send(method string) {
+	// copy of sent values, request is marshalled later by GateBroker goroutine
+	gt.block.Lock()
 	req := gt.Req
-	req.Thermo = gt
+	req.Thermo = &Thermo{
+		CluObject:    CluObject{Id: gt.Id, Name: gt.Name, Kind: gt.Kind},
+		Source:       gt.Source,
+		TempSetpoint: gt.TempSetpoint,
+		TempHoliday:  gt.TempHoliday,
+		Mode:         gt.Mode,
+		State:        gt.State,
+		Direction:    gt.Direction,
+	}
+	gt.block.Unlock()
+
 	obj, err := gt.SendReq(req)
 
 	if err != nil {
-		gt.clu.set.Error(fmt.Errorf("Thermo SetState: %w", err))
+		gt.clu.set.Error(fmt.Errorf("Thermo %s: %w", method, err))
 		return
 	}
 
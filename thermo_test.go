@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/brutella/hap/characteristic"
+)
+
+func newTestThermo(modes map[string]ThermoMode) *Thermo {
+	gt := &Thermo{Modes: modes}
+	gt.initModes()
+	return gt
+}
+
+func TestThermoHkTarget(t *testing.T) {
+	cooling := map[string]ThermoMode{
+		"cool": {State: 1, Mode: 0, Direction: 1},
+	}
+
+	tests := []struct {
+		name    string
+		modes   map[string]ThermoMode
+		current ThermoMode
+		want    int
+		mapped  bool
+	}{
+		{"off", nil, ThermoMode{State: 0}, characteristic.TargetHeatingCoolingStateOff, true},
+		{"off ignores mode", nil, ThermoMode{State: 0, Mode: 1, Direction: 1}, characteristic.TargetHeatingCoolingStateOff, true},
+		{"manual is heat", nil, ThermoMode{State: 1, Mode: 0}, characteristic.TargetHeatingCoolingStateHeat, true},
+		{"schedule is auto", nil, ThermoMode{State: 1, Mode: 1}, characteristic.TargetHeatingCoolingStateAuto, true},
+		{"holiday is auto", nil, ThermoMode{State: 1, Mode: 2}, characteristic.TargetHeatingCoolingStateAuto, true},
+		{"unmapped mode", nil, ThermoMode{State: 1, Mode: 3}, 0, false},
+		{"unmapped direction", nil, ThermoMode{State: 1, Mode: 0, Direction: 1}, 0, false},
+		{"configured cooling", cooling, ThermoMode{State: 1, Mode: 0, Direction: 1}, characteristic.TargetHeatingCoolingStateCool, true},
+		{"heat with cooling configured", cooling, ThermoMode{State: 1, Mode: 0}, characteristic.TargetHeatingCoolingStateHeat, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gt := newTestThermo(tt.modes)
+			got, ok := gt.hkTarget(tt.current)
+			if ok != tt.mapped || (ok && got != tt.want) {
+				t.Errorf("hkTarget(%+v) = (%d, %v), want (%d, %v)", tt.current, got, ok, tt.want, tt.mapped)
+			}
+		})
+	}
+}
+
+func TestThermoApplyTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   int
+		want    ThermoMode
+		invalid bool
+	}{
+		{"off keeps mode", characteristic.TargetHeatingCoolingStateOff, ThermoMode{State: 0, Mode: 1}, false},
+		{"heat", characteristic.TargetHeatingCoolingStateHeat, ThermoMode{State: 1, Mode: 0}, false},
+		{"auto", characteristic.TargetHeatingCoolingStateAuto, ThermoMode{State: 1, Mode: 1}, false},
+		{"cool is not mapped by default", characteristic.TargetHeatingCoolingStateCool, ThermoMode{State: 1, Mode: 1}, true},
+		{"out of range", len(thermoStates), ThermoMode{State: 1, Mode: 1}, true},
+		{"negative", -1, ThermoMode{State: 1, Mode: 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// thermostat follows schedule before the change
+			gt := newTestThermo(nil)
+			gt.State, gt.Mode = 1, 1
+			gt.targetState = characteristic.TargetHeatingCoolingStateAuto
+
+			err := gt.applyTarget(tt.state)
+			if (err != nil) != tt.invalid {
+				t.Fatalf("applyTarget(%d) error = %v, want invalid: %v", tt.state, err, tt.invalid)
+			}
+
+			got := ThermoMode{State: gt.State, Mode: gt.Mode, Direction: gt.Direction}
+			if got != tt.want {
+				t.Errorf("applyTarget(%d) set %+v, want %+v", tt.state, got, tt.want)
+			}
+			if !tt.invalid && gt.targetState != tt.state {
+				t.Errorf("targetState = %d, want %d", gt.targetState, tt.state)
+			}
+		})
+	}
+}
+
+func TestThermoApplyTargetRoundTrip(t *testing.T) {
+	for state, name := range thermoStates {
+		gt := newTestThermo(map[string]ThermoMode{"cool": {State: 1, Direction: 1}})
+		if err := gt.applyTarget(state); err != nil {
+			t.Fatalf("applyTarget(%s): %v", name, err)
+		}
+
+		got, ok := gt.hkTarget(ThermoMode{State: gt.State, Mode: gt.Mode, Direction: gt.Direction})
+		if !ok || got != state {
+			t.Errorf("%s: Grenton values read back as (%d, %v)", name, got, ok)
+		}
+	}
+}
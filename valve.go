@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+	"github.com/brutella/hap/service"
+)
+
+const defaultValveDuration = 600
+
+// Valve is an irrigation valve driven by Grenton relay, run duration is enforced by grengate
+type Valve struct {
+	CluObject
+
+	// Duration is a config option, default run time in seconds (can be changed in HomeKit)
+	Duration int `json:",omitempty"`
+
+	State bool
+
+	endTime  time.Time
+	runTimer *time.Timer
+	// closing is set until relay off is confirmed by CLU
+	closing bool
+	// resending is set while repeated closing request is in flight
+	resending bool
+	block     sync.Mutex
+
+	system      *IrrigationSystem
+	hkAccessory *accessory.A
+	hkService   *service.Valve
+	hkSet       *characteristic.SetDuration
+	hkRemaining *characteristic.RemainingDuration
+	hkFault     *characteristic.StatusFault
+}
+
+func (vl *Valve) Init(clu *Clu) {
+	vl.clu = clu
+
+	vl.Req = ReqObject{
+		Kind: "Valve",
+		Clu:  vl.clu.Id,
+		Id:   vl.GetMixedId(),
+	}
+
+	if vl.Duration <= 0 {
+		vl.Duration = defaultValveDuration
+	}
+
+	vl.appendService()
+}
+
+// GetA returns standalone valve accessory, nil when valve is a part of irrigation system
+func (vl *Valve) GetA() *accessory.A {
+	return vl.hkAccessory
+}
+
+func (vl *Valve) appendService() {
+	vl.hkService = service.NewValve()
+	vl.hkService.ValveType.SetValue(characteristic.ValveTypeIrrigation)
+
+	vl.hkSet = characteristic.NewSetDuration()
+	vl.hkSet.SetValue(vl.Duration)
+	vl.hkService.AddC(vl.hkSet.C)
+
+	vl.hkRemaining = characteristic.NewRemainingDuration()
+	vl.hkRemaining.ValueRequestFunc = func(*http.Request) (interface{}, int) {
+		vl.block.Lock()
+		defer vl.block.Unlock()
+		return vl.remaining(), 0
+	}
+	vl.hkService.AddC(vl.hkRemaining.C)
+
+	vl.hkFault = characteristic.NewStatusFault()
+	vl.hkFault.SetValue(characteristic.StatusFaultNoFault)
+	vl.hkService.AddC(vl.hkFault.C)
+
+	vl.hkService.Active.OnValueRemoteUpdate(vl.SetActive)
+	vl.hkSet.OnValueRemoteUpdate(vl.SetDuration)
+	vl.guardWrites(vl.hkService.Active.C)
+}
+
+// appendHk creates standalone accessory for the valve
+func (vl *Valve) appendHk() *accessory.A {
+	info := accessory.Info{
+		Name:         vl.Name,
+		SerialNumber: fmt.Sprintf("%d", vl.Id),
+		Manufacturer: "Grenton",
+		Model:        vl.Kind,
+	}
+
+	vl.hkAccessory = accessory.New(info, accessory.TypeSprinkler)
+	vl.hkAccessory.Id = vl.GetLongId()
+	vl.hkAccessory.AddS(vl.hkService.S)
+
+	vl.clu.set.Logf("HK Valve added (id: %x)", vl.hkAccessory.Id)
+	return vl.hkAccessory
+}
+
+// IsOpen returns valve state, it is used by irrigation system
+func (vl *Valve) IsOpen() bool {
+	vl.block.Lock()
+	defer vl.block.Unlock()
+
+	return vl.State
+}
+
+// remaining returns run time left in seconds, lock must be held
+func (vl *Valve) remaining() int {
+	if !vl.State || vl.endTime.IsZero() {
+		return 0
+	}
+	left := int(time.Until(vl.endTime).Seconds())
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// Sync sets HK accessory values based on Valve values, lock must be held
+func (vl *Valve) Sync() {
+	if vl.State {
+		vl.hkService.Active.SetValue(characteristic.ActiveActive)
+		vl.hkService.InUse.SetValue(characteristic.InUseInUse)
+	} else {
+		vl.hkService.Active.SetValue(characteristic.ActiveInactive)
+		vl.hkService.InUse.SetValue(characteristic.InUseNotInUse)
+	}
+	vl.hkRemaining.SetValue(vl.remaining())
+}
+
+// syncSystem updates irrigation system the valve belongs to, valve lock must not be held
+func (vl *Valve) syncSystem() {
+	if vl.system != nil {
+		vl.system.Sync()
+	}
+}
+
+// SetFault sets HomeKit StatusFault characteristic (also of irrigation system), HomeKit writes are rejected during fault
+func (vl *Valve) SetFault(fault bool) {
+	vl.CluObject.SetFault(fault)
+	if fault {
+		vl.hkFault.SetValue(characteristic.StatusFaultGeneralFault)
+	} else {
+		vl.hkFault.SetValue(characteristic.StatusFaultNoFault)
+	}
+
+	vl.syncSystem()
+}
+
+// SetDuration sets run time (in seconds) used for next runs
+func (vl *Valve) SetDuration(duration int) {
+	vl.block.Lock()
+	defer vl.block.Unlock()
+
+	vl.Duration = duration
+}
+
+// SetActive opens the valve for Duration or closes it
+func (vl *Valve) SetActive(active int) {
+	vl.block.Lock()
+	if active == characteristic.ActiveActive {
+		vl.start()
+	} else {
+		vl.stop()
+	}
+	vl.Sync()
+	vl.block.Unlock()
+
+	vl.syncSystem()
+	vl.send()
+}
+
+// start sets state and run timer, lock must be held
+func (vl *Valve) start() {
+	vl.State = true
+	vl.closing = false
+	if vl.runTimer != nil {
+		vl.runTimer.Stop()
+	}
+	duration := time.Duration(vl.Duration) * time.Second
+	vl.endTime = time.Now().Add(duration)
+	vl.runTimer = time.AfterFunc(duration, vl.finished)
+}
+
+// stop clears state and run timer, valve is closing until CLU reports relay off, lock must be held
+func (vl *Valve) stop() {
+	vl.State = false
+	vl.closing = true
+	if vl.runTimer != nil {
+		vl.runTimer.Stop()
+		vl.runTimer = nil
+	}
+	vl.endTime = time.Time{}
+}
+
+// finished is called by run timer, relay is switched off
+func (vl *Valve) finished() {
+	vl.clu.set.Logf("Valve %s: run time elapsed, closing", vl.Name)
+	vl.SetActive(characteristic.ActiveInactive)
+}
+
+// resendClose repeats closing request, next one is not started until it is finished
+func (vl *Valve) resendClose() {
+	vl.send()
+
+	vl.block.Lock()
+	vl.resending = false
+	vl.block.Unlock()
+}
+
+func (vl *Valve) send() {
+	// copy of sent values, request is marshalled later by GateBroker goroutine
+	vl.block.Lock()
+	req := vl.Req
+	req.Valve = &Valve{CluObject: CluObject{Id: vl.Id, Name: vl.Name, Kind: vl.Kind}, State: vl.State}
+	vl.block.Unlock()
+
+	_, err := vl.SendReq(req)
+
+	if err != nil {
+		vl.clu.set.Error(fmt.Errorf("Valve send: %w", err))
+	}
+}
+
+// LoadReqObject checks object received from http request end reads it into Valve
+func (vl *Valve) LoadReqObject(obj ReqObject) error {
+	if obj.Kind != "Valve" {
+		return fmt.Errorf("Valve LoadReqObject: wrong object kind (%s)", obj.Kind)
+	}
+
+	if obj.Valve == nil {
+		return fmt.Errorf("Valve LoadReqObject: missing Valve object")
+	}
+
+	vl.clu.set.Debugf("Valve LoadReqObject loading: \n%+v", obj)
+
+	vl.SetFault(false)
+
+	vl.block.Lock()
+	switch {
+	case obj.Valve.State && vl.closing:
+		// closing request failed, it is repeated instead of starting new run
+		if !vl.resending {
+			vl.resending = true
+			vl.clu.set.Logf("Valve %s: still open after closing, repeating request", vl.Name)
+			go vl.resendClose()
+		}
+	case obj.Valve.State && vl.runTimer == nil:
+		// valve opened outside of grengate, run time is enforced anyway
+		vl.start()
+	case !obj.Valve.State:
+		if vl.runTimer != nil {
+			vl.stop()
+		}
+		vl.closing = false
+	}
+	vl.Sync()
+	vl.block.Unlock()
+
+	vl.syncSystem()
+
+	return nil
+}